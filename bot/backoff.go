@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultMinDelay = time.Second
+	defaultMaxDelay = time.Minute
+)
+
+// Backoff yields capped exponential delays with jitter between reconnect
+// attempts. Half of every delay is fixed and half is random, so several
+// bridges restarting together don't hammer the server in lockstep.
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+
+	attempt int
+}
+
+func (b *Backoff) Next() time.Duration {
+	min, max := b.Min, b.Max
+	if min <= 0 {
+		min = defaultMinDelay
+	}
+	if max < min {
+		max = min
+	}
+
+	delay := min
+	for i := 0; i < b.attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	b.attempt++
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
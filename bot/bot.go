@@ -2,33 +2,159 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"log/slog"
+	"sync"
+	"time"
 )
 
+var ErrNoValue = errors.New("bot: server printed no value")
+
+const defaultExecWindow = time.Millisecond * 500
+
 type Bot struct {
 	econ        *econ.ECON
 	serverType  econ.ServerType
 	receiveChan chan string
-	sendChan    chan string
+	sendChan    chan econ.Event
+	backoff     Backoff
+	execWindow  time.Duration
+	execMu      sync.Mutex
+
+	mu             sync.Mutex
+	reconnects     int
+	lastError      error
+	connectedSince time.Time
+	capture        chan []byte
 }
 
 type BotOpts struct {
 	Econ        *econ.ECON
 	ServerType  econ.ServerType
 	ReceiveChan chan string
-	SendChan    chan string
+	SendChan    chan econ.Event
+
+	// ReconnectMinDelay and ReconnectMaxDelay bound the backoff between
+	// ECON reconnect attempts. Zero values fall back to 1s and 1m.
+	ReconnectMinDelay time.Duration
+	ReconnectMaxDelay time.Duration
+
+	// ExecWindow is how long console output is collected after a command
+	// is run with Exec. Defaults to 500ms.
+	ExecWindow time.Duration
+}
+
+type Stats struct {
+	Connected      bool
+	Reconnects     int
+	LastError      error
+	ConnectedSince time.Time
+}
+
+func (s Stats) Uptime() time.Duration {
+	if !s.Connected {
+		return 0
+	}
+
+	return time.Since(s.ConnectedSince)
 }
 
 func NewBot(opts BotOpts) *Bot {
+	minDelay, maxDelay := opts.ReconnectMinDelay, opts.ReconnectMaxDelay
+	if minDelay <= 0 {
+		minDelay = defaultMinDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxDelay
+	}
+
+	execWindow := opts.ExecWindow
+	if execWindow <= 0 {
+		execWindow = defaultExecWindow
+	}
+
 	return &Bot{
 		econ:        opts.Econ,
 		receiveChan: opts.ReceiveChan,
 		sendChan:    opts.SendChan,
 		serverType:  opts.ServerType,
+		execWindow:  execWindow,
+		backoff: Backoff{
+			Min: minDelay,
+			Max: maxDelay,
+		},
+	}
+}
+
+func (b *Bot) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return Stats{
+		Connected:      !b.connectedSince.IsZero(),
+		Reconnects:     b.reconnects,
+		LastError:      b.lastError,
+		ConnectedSince: b.connectedSince,
 	}
 }
 
+// Start keeps the bot connected until ctx is done, reconnecting with
+// backoff whatever the failure, so one broken server doesn't take down
+// the others.
 func (b *Bot) Start(ctx context.Context) error {
+	go b.forward(ctx)
+
+	offline := false
+
+	for {
+		err := b.session(ctx, &offline)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// the other servers keep running, the password may be fixed on
+		// the server side
+		if errors.Is(err, econ.ErrWrongPassword) {
+			slog.Error("ECON rejected the password, check the config")
+		}
+
+		stats := b.Stats()
+
+		b.mu.Lock()
+		b.lastError = err
+		b.connectedSince = time.Time{}
+		b.mu.Unlock()
+
+		if !offline {
+			offline = true
+			b.notify(ctx, econ.EventOffline)
+		}
+
+		delay := b.backoff.Next()
+		slog.Warn(
+			"ECON connection lost, reconnecting",
+			slog.String("err", err.Error()),
+			slog.Int("attempt", b.backoff.Attempt()),
+			slog.Duration("delay", delay),
+			slog.Duration("uptime", stats.Uptime()),
+			slog.Int("reconnects", stats.Reconnects),
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		b.mu.Lock()
+		b.reconnects++
+		b.mu.Unlock()
+	}
+}
+
+// session runs a single ECON connection until it drops or ctx is done.
+func (b *Bot) session(ctx context.Context, offline *bool) error {
 	err := b.econ.Connect()
 	if err != nil {
 		return err
@@ -36,43 +162,171 @@ func (b *Bot) Start(ctx context.Context) error {
 
 	defer b.econ.Disconnect()
 
-	errch := make(chan error)
+	b.backoff.Reset()
+
+	b.mu.Lock()
+	b.connectedSince = time.Now()
+	b.mu.Unlock()
 
+	slog.Info("Connected to ECON", slog.Int("reconnects", b.Stats().Reconnects))
+
+	if *offline {
+		*offline = false
+		b.notify(ctx, econ.EventOnline)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// unblock pending Read on shutdown
 	go func() {
-		for b.econ.Connected() {
-			select {
-			case <-ctx.Done():
-				return
-			case x := <-b.receiveChan:
-				err := b.econ.Message(x)
-				if err != nil {
-					errch <- err
-					return
-				}
-			}
+		select {
+		case <-ctx.Done():
+			b.econ.Disconnect()
+		case <-done:
 		}
 	}()
 
-	for b.econ.Connected() {
+	lines, errch := b.econ.Lines(ctx)
+
+	for line := range lines {
+		b.captureLine(line)
+
+		if b.econ.Echo(line) {
+			continue
+		}
+
+		event, ok := econ.Adapters[b.serverType].Match(line)
+		if !ok {
+			continue
+		}
+
 		select {
 		case <-ctx.Done():
-			return nil
-		case err := <-errch:
-			return err
-		default:
-			msg, err := b.econ.Read()
-			if err != nil {
-				return err
-			}
+		case b.sendChan <- event:
+		}
+	}
 
-			text, ok := econ.Adapters[b.serverType].Match(msg)
-			if !ok {
+	return <-errch
+}
+
+// forward relays Telegram messages to the game for as long as the bot runs,
+// dropping them while the server is unreachable.
+func (b *Bot) forward(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case x := <-b.receiveChan:
+			if !b.econ.Connected() {
+				slog.Warn("ECON is offline, dropping message")
 				continue
 			}
 
-			b.sendChan <- text
+			err := b.econ.Message(x)
+			if err != nil {
+				slog.Error(
+					"Failed to send message to ECON!",
+					slog.String(
+						"err",
+						err.Error(),
+					),
+				)
+			}
+		}
+	}
+}
+
+func (b *Bot) notify(ctx context.Context, kind econ.EventKind) {
+	select {
+	case <-ctx.Done():
+	case b.sendChan <- econ.NewEvent(kind, nil):
+	}
+}
+
+// Exec runs a console command and returns the lines the server printed
+// during the exec window. Commands are run one at a time, so output of
+// concurrent calls doesn't interleave.
+func (b *Bot) Exec(ctx context.Context, command string) ([]string, error) {
+	if econ.HasControl(command) {
+		return nil, econ.ErrControlCharacter
+	}
+
+	b.execMu.Lock()
+	defer b.execMu.Unlock()
+
+	capture := make(chan []byte, 256)
+
+	b.mu.Lock()
+	b.capture = capture
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.capture = nil
+		b.mu.Unlock()
+	}()
+
+	if err := b.econ.Write([]byte(command)); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(b.execWindow)
+	defer timer.Stop()
+
+	var output []string
+
+	for {
+		select {
+		case <-ctx.Done():
+			return output, ctx.Err()
+		case <-timer.C:
+			return output, nil
+		case line := <-capture:
+			output = append(output, string(line))
 		}
 	}
+}
+
+// Connected reports whether the ECON connection is up.
+func (b *Bot) Connected() bool {
+	return b.Stats().Connected
+}
+
+func (b *Bot) Players(ctx context.Context) ([]econ.Player, error) {
+	output, err := b.Exec(ctx, "status")
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	return econ.ParseStatus(b.serverType, output), nil
+}
+
+func (b *Bot) Map(ctx context.Context) (string, error) {
+	output, err := b.Exec(ctx, "sv_map")
+	if err != nil {
+		return "", err
+	}
+
+	name, ok := econ.ParseValue(output)
+	if !ok {
+		return "", ErrNoValue
+	}
+
+	return name, nil
+}
+
+func (b *Bot) captureLine(line []byte) {
+	b.mu.Lock()
+	capture := b.capture
+	b.mu.Unlock()
+
+	if capture == nil {
+		return
+	}
+
+	select {
+	case capture <- line:
+	default:
+	}
 }
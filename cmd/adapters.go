@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+)
+
+// registerAdapters adds the server types defined under "adapters".
+func registerAdapters() error {
+	var adapters map[econ.ServerType]econ.CustomAdapterOpts
+	if err := viper.UnmarshalKey("adapters", &adapters); err != nil {
+		return err
+	}
+
+	for serverType, opts := range adapters {
+		adapter, err := econ.NewCustomAdapter(opts)
+		if err != nil {
+			return fmt.Errorf("adapter %q: %w", serverType, err)
+		}
+
+		if err := econ.RegisterAdapter(serverType, adapter); err != nil {
+			return fmt.Errorf("adapter %q: %w", serverType, err)
+		}
+	}
+
+	return nil
+}
@@ -7,10 +7,12 @@ import (
 	"github.com/spf13/viper"
 	"github.com/xbt573/tw-econ-telegram-bridge/bot"
 	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"github.com/xbt573/tw-econ-telegram-bridge/store"
 	"github.com/xbt573/tw-econ-telegram-bridge/telegram"
 	"log/slog"
 	"os"
 	"os/signal"
+	"sync"
 )
 
 var (
@@ -36,7 +38,7 @@ func init() {
 	rootCmd.PersistentFlags().String(
 		"type",
 		"",
-		"Server type (one of 'ddnet', 'trainfng', or 'teeworlds'",
+		"Server type (one of 'ddnet', 'trainfng', 'teeworlds' or a type from 'adapters'",
 	)
 	viper.BindPFlag("chat_id", rootCmd.PersistentFlags().Lookup("chatid"))
 	viper.BindPFlag("thread_id", rootCmd.PersistentFlags().Lookup("threadid"))
@@ -81,14 +83,21 @@ var rootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		slog.Info("Starting bridge...")
 
-		econInstance, err := econ.NewECON(econ.ECONOpts{
-			Ip:       viper.GetString("ip"),
-			Port:     viper.GetUint16("port"),
-			Password: viper.GetString("password"),
-		})
+		if err := registerAdapters(); err != nil {
+			slog.Error(
+				"Failed to register adapters!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		servers, err := loadServers()
 		if err != nil {
 			slog.Error(
-				"Failed to init ECON!",
+				"Failed to parse servers!",
 				slog.String(
 					"err",
 					err.Error(),
@@ -97,23 +106,178 @@ var rootCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		emojiAliases := viper.GetStringMapString("emoji.aliases")
+
 		var (
-			sendChan    = make(chan string)
-			receiveChan = make(chan string)
+			bots   []*bot.Bot
+			routes []telegram.RouteOpts
 		)
 
-		tgInstance, err := telegram.NewTelegram(telegram.TelegramOpts{
-			Token:       viper.GetString("token"),
-			ServerName:  viper.GetString("server_name"),
-			ThreadId:    viper.GetInt64("thread_id"),
-			ChatId:      viper.GetInt64("chat_id"),
-			ReceiveChan: receiveChan,
-			SendChan:    sendChan,
-			BotOpts:     nil,
-		})
+		for _, server := range servers {
+			econInstance, err := econ.NewECON(econ.ECONOpts{
+				Ip:       server.Ip,
+				Port:     server.Port,
+				Password: server.Password,
+
+				ChatLimit: chatLimit(server.Type),
+				MaxLines:  viper.GetInt("chat.max_lines"),
+				Throttle:  viper.GetDuration("chat.throttle"),
+			})
+			if err != nil {
+				slog.Error(
+					"Failed to init ECON!",
+					slog.String("server", server.Name),
+					slog.String(
+						"err",
+						err.Error(),
+					),
+				)
+				os.Exit(1)
+			}
+
+			var (
+				sendChan    = make(chan string)
+				receiveChan = make(chan econ.Event)
+			)
+
+			botInstance := bot.NewBot(bot.BotOpts{
+				Econ:        econInstance,
+				ServerType:  server.Type,
+				ReceiveChan: sendChan,
+				SendChan:    receiveChan,
+
+				ReconnectMinDelay: viper.GetDuration("reconnect_min_delay"),
+				ReconnectMaxDelay: viper.GetDuration("reconnect_max_delay"),
+				ExecWindow:        viper.GetDuration("rcon.window"),
+			})
+
+			emoji, err := telegram.NewEmojiConverter(server.Emoji, emojiAliases)
+			if err != nil {
+				slog.Error(
+					"Failed to init emoji converter!",
+					slog.String("server", server.Name),
+					slog.String(
+						"err",
+						err.Error(),
+					),
+				)
+				os.Exit(1)
+			}
+
+			bots = append(bots, botInstance)
+			routes = append(routes, telegram.RouteOpts{
+				ServerName:  server.Name,
+				ChatId:      server.ChatId,
+				ThreadId:    server.ThreadId,
+				Server:      botInstance,
+				ReceiveChan: receiveChan,
+				SendChan:    sendChan,
+				Emoji:       emoji,
+				Events:      server.Events,
+				Channels:    server.Channels,
+			})
+		}
+
+		stateStore, err := store.Open(viper.GetString("state_file"))
 		if err != nil {
 			slog.Error(
-				"Failed to init Telegram!",
+				"Failed to open state file!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		var status telegram.StatusOpts
+		if err := viper.UnmarshalKey("status", &status); err != nil {
+			slog.Error(
+				"Failed to parse status options!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		var webhook telegram.WebhookOpts
+		if err := viper.UnmarshalKey("webhook", &webhook); err != nil {
+			slog.Error(
+				"Failed to parse webhook options!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		var outbox telegram.OutboxOpts
+		if err := viper.UnmarshalKey("outbox", &outbox); err != nil {
+			slog.Error(
+				"Failed to parse outbox options!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		var templateOpts telegram.TemplateOpts
+		if err := viper.UnmarshalKey("templates", &templateOpts); err != nil {
+			slog.Error(
+				"Failed to parse templates!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		templates, err := telegram.NewTemplates(templateOpts)
+		if err != nil {
+			slog.Error(
+				"Invalid templates!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		var mentions telegram.MentionOpts
+		if err := viper.UnmarshalKey("reply", &mentions); err != nil {
+			slog.Error(
+				"Failed to parse reply options!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		var media telegram.MediaOpts
+		if err := viper.UnmarshalKey("media", &media); err != nil {
+			slog.Error(
+				"Failed to parse media options!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		var edit telegram.EditOpts
+		if err := viper.UnmarshalKey("edit", &edit); err != nil {
+			slog.Error(
+				"Failed to parse edit options!",
 				slog.String(
 					"err",
 					err.Error(),
@@ -122,15 +286,50 @@ var rootCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		botInstance := bot.NewBot(bot.BotOpts{
-			Econ:        econInstance,
-			ServerType:  econ.ServerType(viper.GetString("type")),
-			ReceiveChan: sendChan,
-			SendChan:    receiveChan,
+		var vote telegram.VoteOpts
+		if err := viper.UnmarshalKey("vote", &vote); err != nil {
+			slog.Error(
+				"Failed to parse vote options!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		var rconUsers []telegram.RconUser
+		if err := viper.UnmarshalKey("rcon.users", &rconUsers); err != nil {
+			slog.Error(
+				"Failed to parse rcon users!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+			os.Exit(1)
+		}
+
+		tgInstance, err := telegram.NewTelegram(telegram.TelegramOpts{
+			Token:       viper.GetString("token"),
+			BotOpts:     nil,
+			Routes:      routes,
+			RconUsers:   rconUsers,
+			ShowAddress: viper.GetBool("players.show_address"),
+			Store:       stateStore,
+			Status:      status,
+			Webhook:     webhook,
+			Outbox:      outbox,
+			Templates:   templates,
+			QuoteLength: viper.GetInt("reply.quote_length"),
+			Mentions:    mentions,
+			Media:       media,
+			Edit:        edit,
+			Vote:        vote,
 		})
 		if err != nil {
 			slog.Error(
-				"Failed to init bot!",
+				"Failed to init Telegram!",
 				slog.String(
 					"err",
 					err.Error(),
@@ -139,26 +338,38 @@ var rootCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		errch := make(chan error)
+		var (
+			wg sync.WaitGroup
+			// buffered, so failures after the first don't block shutdown
+			errch = make(chan error, len(bots)+1)
+		)
 
-		sigch := make(chan os.Signal)
+		sigch := make(chan os.Signal, 1)
 		signal.Notify(sigch, os.Interrupt)
 
 		ctx, cancel := context.WithCancel(context.Background())
 
+		wg.Add(1 + len(bots))
+
 		go func() {
+			defer wg.Done()
+
 			err := tgInstance.Start(ctx)
 			if err != nil {
 				errch <- err
 			}
 		}()
 
-		go func() {
-			err := botInstance.Start(ctx)
-			if err != nil {
-				errch <- err
-			}
-		}()
+		for i, botInstance := range bots {
+			go func(name string, botInstance *bot.Bot) {
+				defer wg.Done()
+
+				err := botInstance.Start(ctx)
+				if err != nil {
+					errch <- fmt.Errorf("%v: %w", name, err)
+				}
+			}(servers[i].Name, botInstance)
+		}
 
 		slog.Info("Started!")
 
@@ -176,6 +387,9 @@ var rootCmd = &cobra.Command{
 			)
 			cancel()
 		}
+
+		// let Telegram remove its webhook and the bots disconnect
+		wg.Wait()
 	},
 }
 
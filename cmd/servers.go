@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"github.com/xbt573/tw-econ-telegram-bridge/telegram"
+)
+
+// ServerConfig is a single entry of the "servers" list. Without the list
+// a single server is read from the top-level keys.
+type ServerConfig struct {
+	Name     string          `mapstructure:"name"`
+	Ip       string          `mapstructure:"ip"`
+	Port     uint16          `mapstructure:"port"`
+	Password string          `mapstructure:"password"`
+	Type     econ.ServerType `mapstructure:"type"`
+	ChatId   int64           `mapstructure:"chat_id"`
+	ThreadId int64           `mapstructure:"thread_id"`
+
+	// Emoji overrides the default emoji mode for this server
+	Emoji telegram.EmojiMode `mapstructure:"emoji"`
+	// Events overrides the default "events" list for this server
+	Events []econ.EventKind `mapstructure:"events"`
+	// Channels overrides the default "channels" list for this server
+	Channels []econ.Channel `mapstructure:"channels"`
+}
+
+func loadServers() ([]ServerConfig, error) {
+	var servers []ServerConfig
+
+	if !viper.IsSet("servers") {
+		servers = []ServerConfig{{
+			Name:     viper.GetString("server_name"),
+			Ip:       viper.GetString("ip"),
+			Port:     viper.GetUint16("port"),
+			Password: viper.GetString("password"),
+			Type:     econ.ServerType(viper.GetString("type")),
+			ChatId:   viper.GetInt64("chat_id"),
+			ThreadId: viper.GetInt64("thread_id"),
+		}}
+	} else if err := viper.UnmarshalKey("servers", &servers); err != nil {
+		return nil, err
+	}
+
+	if len(servers) == 0 {
+		return nil, errors.New("no servers configured")
+	}
+
+	for i, server := range servers {
+		if server.Name == "" {
+			servers[i].Name = fmt.Sprintf("%v:%v", server.Ip, server.Port)
+		}
+
+		if server.Emoji == "" {
+			servers[i].Emoji = telegram.EmojiMode(viper.GetString("emoji.mode"))
+		}
+
+		if server.Events == nil && viper.IsSet("events") {
+			for _, kind := range viper.GetStringSlice("events") {
+				servers[i].Events = append(servers[i].Events, econ.EventKind(kind))
+			}
+		}
+
+		if server.Channels == nil && viper.IsSet("channels") {
+			for _, channel := range viper.GetStringSlice("channels") {
+				servers[i].Channels = append(servers[i].Channels, econ.Channel(channel))
+			}
+		}
+
+		if _, ok := econ.Adapters[server.Type]; !ok {
+			return nil, fmt.Errorf("server %q: unknown type %q", servers[i].Name, server.Type)
+		}
+	}
+
+	return servers, nil
+}
+
+// chatLimit returns the configured chat line limit for the server type,
+// falling back to the built-in default.
+func chatLimit(serverType econ.ServerType) int {
+	key := "chat.limits." + string(serverType)
+	if viper.IsSet(key) {
+		return viper.GetInt(key)
+	}
+
+	return econ.DefaultChatLimits[serverType]
+}
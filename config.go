@@ -1,6 +1,11 @@
 package main
 
-import "github.com/xbt573/tw-econ-telegram-bridge/econ"
+import (
+	"github.com/xbt573/tw-econ-telegram-bridge/cmd"
+	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"github.com/xbt573/tw-econ-telegram-bridge/telegram"
+	"time"
+)
 
 type Config struct {
 	ChatId   int64           `yaml:"chat_id"`
@@ -10,4 +15,54 @@ type Config struct {
 	Password string          `yaml:"password"`
 	Token    string          `yaml:"token"`
 	Type     econ.ServerType `yaml:"type"`
+
+	ServerName string `yaml:"server_name"`
+	StateFile  string `yaml:"state_file"`
+
+	// Servers replaces the single server keys above when set
+	Servers []cmd.ServerConfig `yaml:"servers"`
+
+	// Adapters define extra server types
+	Adapters map[econ.ServerType]econ.CustomAdapterOpts `yaml:"adapters"`
+
+	Events   []econ.EventKind `yaml:"events"`
+	Channels []econ.Channel   `yaml:"channels"`
+
+	ReconnectMinDelay time.Duration `yaml:"reconnect_min_delay"`
+	ReconnectMaxDelay time.Duration `yaml:"reconnect_max_delay"`
+
+	Rcon struct {
+		Window time.Duration       `yaml:"window"`
+		Users  []telegram.RconUser `yaml:"users"`
+	} `yaml:"rcon"`
+
+	Players struct {
+		ShowAddress bool `yaml:"show_address"`
+	} `yaml:"players"`
+
+	Status  telegram.StatusOpts  `yaml:"status"`
+	Webhook telegram.WebhookOpts `yaml:"webhook"`
+	Outbox  telegram.OutboxOpts  `yaml:"outbox"`
+
+	Chat struct {
+		Limits   map[econ.ServerType]int `yaml:"limits"`
+		MaxLines int                     `yaml:"max_lines"`
+		Throttle time.Duration           `yaml:"throttle"`
+	} `yaml:"chat"`
+
+	Emoji struct {
+		Mode    telegram.EmojiMode `yaml:"mode"`
+		Aliases map[string]string  `yaml:"aliases"`
+	} `yaml:"emoji"`
+
+	Templates telegram.TemplateOpts `yaml:"templates"`
+	Media     telegram.MediaOpts    `yaml:"media"`
+	Edit      telegram.EditOpts     `yaml:"edit"`
+	Vote      telegram.VoteOpts     `yaml:"vote"`
+
+	Reply struct {
+		QuoteLength int           `yaml:"quote_length"`
+		Mentions    bool          `yaml:"mentions"`
+		MentionAge  time.Duration `yaml:"mention_age"`
+	} `yaml:"reply"`
 }
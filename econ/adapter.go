@@ -1,17 +1,25 @@
 package econ
 
-import "regexp"
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 var (
-	teeworldsChatRegex  = regexp.MustCompile(`\[chat\]: \d+:-?\d+:(.*)`)
-	teeworldsJoinRegex  = regexp.MustCompile(`\[game\]: team_join player='\d+:(.*)'`)
-	teeworldsLeaveRegex = regexp.MustCompile(`\[game\]: leave player='\d+:(.*)'`)
-
-	trainfngChatRegex = regexp.MustCompile(`\[.*?\]\[chat\]: \d+:-?\d+:(.*)`)
-	trainfngJoinRegex = regexp.MustCompile(`\[.*\]\[.*\]: \*\*\* '(.*)' (.*)`)
-
-	ddnetChatRegex = regexp.MustCompile(`.* I chat: \d+:-?\d+:(.*)`)
-	ddnetJoinRegex = regexp.MustCompile(`.* I chat: \*\*\* '(.*?)' (.*)`)
+	teeworldsChatRegex  = regexp.MustCompile(`\[(chat|teamchat|whisper)\]: (\d+):(-?\d+):(.*?): (.*)`)
+	teeworldsJoinRegex  = regexp.MustCompile(`\[game\]: team_join player='(\d+):(.*)'`)
+	teeworldsLeaveRegex = regexp.MustCompile(`\[game\]: leave player='(\d+):(.*)'`)
+	// "*** text" lines are chat sent by the server itself
+	teeworldsAnnounceRegex = regexp.MustCompile(`\[chat\]: \*\*\* (.*)`)
+
+	trainfngChatRegex     = regexp.MustCompile(`\[.*?\]\[(chat|teamchat|whisper)\]: (\d+):(-?\d+):(.*?): (.*)`)
+	trainfngJoinRegex     = regexp.MustCompile(`\[.*\]\[.*\]: \*\*\* '(.*)' (.*)`)
+	trainfngAnnounceRegex = regexp.MustCompile(`\[.*?\]\[chat\]: \*\*\* (.*)`)
+
+	ddnetChatRegex     = regexp.MustCompile(`.* I (chat|teamchat|whisper): (\d+):(-?\d+):(.*?): (.*)`)
+	ddnetJoinRegex     = regexp.MustCompile(`.* I chat: \*\*\* '(.*?)' (.*)`)
+	ddnetAnnounceRegex = regexp.MustCompile(`.* I chat: \*\*\* (.*)`)
 )
 
 type ServerType string
@@ -23,7 +31,8 @@ const (
 )
 
 type Adapter interface {
-	Match([]byte) (string, bool)
+	Match([]byte) (Event, bool)
+	Player([]byte) (Player, bool)
 }
 
 var Adapters = map[ServerType]Adapter{
@@ -32,55 +41,169 @@ var Adapters = map[ServerType]Adapter{
 	DDNET:     ddnetAdapter{},
 }
 
+// chatEvent builds a chat event from "category", "id", "team", "name"
+// and "text" submatches.
+func chatEvent(raw []byte, match []string) Event {
+	event := NewEvent(EventChat, raw)
+	event.ClientId, _ = strconv.Atoi(match[2])
+	event.Team, _ = strconv.Atoi(match[3])
+	event.Player = match[4]
+	event.Text = match[5]
+	event.Channel = chatChannel(match[1], event.Team)
+
+	return event
+}
+
+// serverChat is a "*** text" line nothing else recognised.
+func serverChat(raw []byte, text string) Event {
+	event := NewEvent(EventInfo, raw)
+	event.Text = text
+	event.Channel = ChannelServer
+
+	return event
+}
+
 type teeworldsAdapter struct{}
 
-func (t teeworldsAdapter) Match(bytes []byte) (string, bool) {
+func (t teeworldsAdapter) Match(bytes []byte) (Event, bool) {
 	match := teeworldsChatRegex.FindStringSubmatch(string(bytes))
 	if len(match) != 0 {
-		return match[1], true
+		return chatEvent(bytes, match), true
+	}
+
+	match = teeworldsAnnounceRegex.FindStringSubmatch(string(bytes))
+	if len(match) != 0 {
+		if event, ok := voteEvent(bytes, match[1]); ok {
+			return event, true
+		}
+
+		// joins and leaves come from the game lines below
+		if strings.HasPrefix(match[1], "'") {
+			return Event{}, false
+		}
+
+		return serverChat(bytes, match[1]), true
+	}
+
+	if event, ok := mapEvent(bytes); ok {
+		return event, true
 	}
 
 	match = teeworldsJoinRegex.FindStringSubmatch(string(bytes))
 	if len(match) != 0 {
-		return match[1] + " joined the game", true
+		event := NewEvent(EventJoin, bytes)
+		event.ClientId, _ = strconv.Atoi(match[1])
+		event.Player = match[2]
+		return event, true
 	}
 
 	match = teeworldsLeaveRegex.FindStringSubmatch(string(bytes))
 	if len(match) != 0 {
-		return match[1] + " left the game", true
+		event := NewEvent(EventLeave, bytes)
+		event.ClientId, _ = strconv.Atoi(match[1])
+		event.Player = match[2]
+		return event, true
+	}
+
+	return Event{}, false
+}
+
+func (teeworldsAdapter) Player(bytes []byte) (Player, bool) {
+	match := teeworldsStatusRegex.FindStringSubmatch(string(bytes))
+	if len(match) == 0 {
+		return Player{}, false
 	}
 
-	return "", false
+	return parsePlayer(match[1])
 }
 
 type trainfngAdapter struct{}
 
-func (trainfngAdapter) Match(bytes []byte) (string, bool) {
+func (trainfngAdapter) Match(bytes []byte) (Event, bool) {
 	match := trainfngChatRegex.FindStringSubmatch(string(bytes))
 	if len(match) != 0 {
-		return match[1], true
+		return chatEvent(bytes, match), true
+	}
+
+	match = trainfngAnnounceRegex.FindStringSubmatch(string(bytes))
+	if len(match) != 0 {
+		if event, ok := voteEvent(bytes, match[1]); ok {
+			return event, true
+		}
+	}
+
+	if event, ok := mapEvent(bytes); ok {
+		return event, true
 	}
 
 	match = trainfngJoinRegex.FindStringSubmatch(string(bytes))
 	if len(match) != 0 {
-		return match[1] + " " + match[2], true
+		return serverMessage(bytes, match[1], match[2]), true
 	}
 
-	return "", false
+	match = trainfngAnnounceRegex.FindStringSubmatch(string(bytes))
+	if len(match) != 0 {
+		return serverChat(bytes, match[1]), true
+	}
+
+	return Event{}, false
+}
+
+func (trainfngAdapter) Player(bytes []byte) (Player, bool) {
+	match := trainfngStatusRegex.FindStringSubmatch(string(bytes))
+	if len(match) == 0 {
+		return Player{}, false
+	}
+
+	return parsePlayer(match[1])
 }
 
 type ddnetAdapter struct{}
 
-func (ddnetAdapter) Match(bytes []byte) (string, bool) {
+func (ddnetAdapter) Match(bytes []byte) (Event, bool) {
 	match := ddnetChatRegex.FindStringSubmatch(string(bytes))
 	if len(match) != 0 {
-		return match[1], true
+		return chatEvent(bytes, match), true
+	}
+
+	match = ddnetKillRegex.FindStringSubmatch(string(bytes))
+	if len(match) != 0 {
+		return killEvent(bytes, match), true
+	}
+
+	match = ddnetAnnounceRegex.FindStringSubmatch(string(bytes))
+	if len(match) != 0 {
+		if event, ok := voteEvent(bytes, match[1]); ok {
+			return event, true
+		}
+
+		if event, ok := raceEvent(bytes, match[1]); ok {
+			return event, true
+		}
+	}
+
+	if event, ok := mapEvent(bytes); ok {
+		return event, true
 	}
 
 	match = ddnetJoinRegex.FindStringSubmatch(string(bytes))
 	if len(match) != 0 {
-		return match[1] + " " + match[2], true
+		return serverMessage(bytes, match[1], match[2]), true
+	}
+
+	match = ddnetAnnounceRegex.FindStringSubmatch(string(bytes))
+	if len(match) != 0 {
+		return serverChat(bytes, match[1]), true
+	}
+
+	return Event{}, false
+}
+
+func (ddnetAdapter) Player(bytes []byte) (Player, bool) {
+	match := ddnetStatusRegex.FindStringSubmatch(string(bytes))
+	if len(match) == 0 {
+		return Player{}, false
 	}
 
-	return "", false
+	return parsePlayer(match[1])
 }
@@ -0,0 +1,44 @@
+package econ
+
+// Channel is who a chat line was meant for.
+type Channel string
+
+const (
+	ChannelPublic Channel = "public"
+	// team chat among spectators, their public chat is ChannelPublic
+	ChannelSpectators Channel = "spectators"
+	ChannelTeam       Channel = "team"
+	ChannelWhisper    Channel = "whisper"
+	// lines the server says itself
+	ChannelServer Channel = "server"
+)
+
+var Channels = []Channel{
+	ChannelPublic,
+	ChannelSpectators,
+	ChannelTeam,
+	ChannelWhisper,
+	ChannelServer,
+}
+
+// Teams of the chat log line, anything from zero up is a team.
+const (
+	teamAll        = -2
+	teamSpectators = -1
+)
+
+// chatChannel tells where a chat line went from the category it was
+// logged under ("chat", "teamchat" or "whisper") and its team. Servers
+// that log whispers as chat use teams below -2 for them.
+func chatChannel(category string, team int) Channel {
+	switch {
+	case category == "whisper" || team < teamAll:
+		return ChannelWhisper
+	case team == teamAll:
+		return ChannelPublic
+	case team == teamSpectators:
+		return ChannelSpectators
+	default:
+		return ChannelTeam
+	}
+}
@@ -0,0 +1,115 @@
+package econ
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// valueRegex only takes console log lines, chat can't fake a value.
+// Teeworlds prints values under "Console", DDNet under "config" (older
+// versions "console").
+var valueRegex = regexp.MustCompile(`^(?:(?:\[[^\]]*\])?\[[Cc]onsole\]|[\d-]+ [\d:]+ I (?:console|config)): Value: (.*)`)
+
+// ErrControlCharacter is returned for commands the console would split
+// at a line break or otherwise mangle.
+var ErrControlCharacter = errors.New("econ: command contains a control character")
+
+// QuoteArg encodes s as a single quoted console argument. The console
+// only knows \\ and \" escapes inside quotes, so those are escaped and
+// control characters (which would end the command line) are replaced by
+// spaces. The result can't terminate the string early or start another
+// command.
+func QuoteArg(s string) string {
+	var builder strings.Builder
+	builder.Grow(len(s) + 2)
+
+	builder.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '"':
+			builder.WriteByte('\\')
+			builder.WriteRune(r)
+		case isControl(r):
+			builder.WriteByte(' ')
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	builder.WriteByte('"')
+
+	return builder.String()
+}
+
+func isControl(r rune) bool {
+	return unicode.IsControl(r) || r == '\u2028' || r == '\u2029'
+}
+
+// HasControl reports whether s contains characters that QuoteArg would
+// replace. Every \r or \n starts a new console line, so commands with
+// them can't be checked by SplitCommands.
+func HasControl(s string) bool {
+	return strings.IndexFunc(s, isControl) != -1
+}
+
+// SplitCommands splits a console line into the commands the server would
+// run. It mirrors the server's splitter: a quote preceded by a backslash
+// never toggles quoting, even outside of a string.
+func SplitCommands(line string) []string {
+	var (
+		commands []string
+		quoted   bool
+		start    int
+	)
+
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if i+1 < len(line) && line[i+1] == '"' {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				commands = appendCommand(commands, line[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return appendCommand(commands, line[start:])
+}
+
+func appendCommand(commands []string, command string) []string {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return commands
+	}
+
+	return append(commands, command)
+}
+
+// CommandName returns the lowercased name of a single console command.
+func CommandName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return strings.ToLower(fields[0])
+}
+
+// ParseValue extracts the value printed when a config variable is run
+// without arguments, e.g. "sv_map".
+func ParseValue(lines []string) (string, bool) {
+	for _, line := range lines {
+		match := valueRegex.FindStringSubmatch(line)
+		if len(match) != 0 {
+			return match[1], true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,139 @@
+package econ
+
+import (
+	"strings"
+	"testing"
+)
+
+// unquote reverses QuoteArg the way the console reads a quoted argument.
+func unquote(t *testing.T, arg string) string {
+	t.Helper()
+
+	if len(arg) < 2 || arg[0] != '"' || arg[len(arg)-1] != '"' {
+		t.Fatalf("argument %q isn't quoted", arg)
+	}
+
+	var (
+		builder strings.Builder
+		body    = arg[1 : len(arg)-1]
+	)
+
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			if i+1 == len(body) || (body[i+1] != '\\' && body[i+1] != '"') {
+				t.Fatalf("argument %q has a dangling escape", arg)
+			}
+			i++
+			builder.WriteByte(body[i])
+		case '"':
+			t.Fatalf("argument %q ends early", arg)
+		default:
+			builder.WriteByte(body[i])
+		}
+	}
+
+	return builder.String()
+}
+
+func TestQuoteArgHostile(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"quote", `"`, `"`},
+		{"backslash", `\`, `\`},
+		{"trailing backslash", `hi \`, `hi \`},
+		{"escaped quote breakout", `\";shutdown;"`, `\";shutdown;"`},
+		{"quote breakout", `";shutdown;"`, `";shutdown;"`},
+		{"newline", "hi\nshutdown", "hi shutdown"},
+		{"carriage return", "hi\rshutdown", "hi shutdown"},
+		{"crlf", "hi\r\nshutdown", "hi  shutdown"},
+		{"nul", "hi\x00shutdown", "hi shutdown"},
+		{"line separator", "hi\u2028shutdown", "hi shutdown"},
+		{"paragraph separator", "hi\u2029shutdown", "hi shutdown"},
+		{"semicolon", "hi;shutdown", "hi;shutdown"},
+		{"quoted semicolon", `"hi;shutdown"`, `"hi;shutdown"`},
+		{"many backslashes", `\\\";shutdown`, `\\\";shutdown`},
+		{"empty", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line := "say " + QuoteArg(test.input)
+
+			if HasControl(line) {
+				t.Fatalf("line %q contains a control character", line)
+			}
+
+			commands := SplitCommands(line)
+			if len(commands) != 1 {
+				t.Fatalf("SplitCommands(%q) = %q, want a single command", line, commands)
+			}
+
+			if name := CommandName(commands[0]); name != "say" {
+				t.Fatalf("command name = %q, want say", name)
+			}
+
+			got := unquote(t, strings.TrimPrefix(commands[0], "say "))
+			if got != test.want {
+				t.Errorf("argument = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"kick 1", []string{"kick 1"}},
+		{"kick 1;shutdown", []string{"kick 1", "shutdown"}},
+		{`say "a;b"`, []string{`say "a;b"`}},
+		{`say \";shutdown`, []string{`say \"`, "shutdown"}},
+		{`say "\";shutdown"`, []string{`say "\";shutdown"`}},
+		{" ; ;", nil},
+	}
+
+	for _, test := range tests {
+		got := SplitCommands(test.line)
+		if strings.Join(got, "\x00") != strings.Join(test.want, "\x00") || len(got) != len(test.want) {
+			t.Errorf("SplitCommands(%q) = %q, want %q", test.line, got, test.want)
+		}
+	}
+}
+
+func TestHasControl(t *testing.T) {
+	for _, line := range []string{"kick 1\nshutdown", "kick 1\rshutdown", "kick\x00", "kick\u2028"} {
+		if !HasControl(line) {
+			t.Errorf("HasControl(%q) = false", line)
+		}
+	}
+
+	if HasControl(`say "hi; there"`) {
+		t.Error("HasControl reported a plain command")
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  string
+	}{
+		{[]string{"[Console]: Value: ctf5"}, "ctf5"},
+		{[]string{"[5f3a1b2c][console]: Value: dm1"}, "dm1"},
+		{[]string{"2024-01-01 00:00:00 I console: Value: Kobra 4"}, "Kobra 4"},
+		{[]string{"2024-01-01 00:00:00 I config: Value: Multeasymap"}, "Multeasymap"},
+		{[]string{"2024-01-01 00:00:00 I chat: 1:0:Bob: Value: fake", "2024-01-01 00:00:00 I console: Value: real"}, "real"},
+		{[]string{"[chat]: 1:0:Bob: [console]: Value: fake"}, ""},
+	}
+
+	for _, test := range tests {
+		got, ok := ParseValue(test.lines)
+		if ok != (test.want != "") || got != test.want {
+			t.Errorf("ParseValue(%q) = %q, %v, want %q", test.lines, got, ok, test.want)
+		}
+	}
+}
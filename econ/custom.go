@@ -0,0 +1,289 @@
+package econ
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var ErrAdapterExists = errors.New("econ: adapter is already registered")
+
+// defaultStatusRegex finds "status" entries in server log lines of any
+// of the built-in formats. Like theirs it's anchored, so chat can't add
+// players.
+var defaultStatusRegex = regexp.MustCompile(`^(?:(?:\[[^\]]*\])?\[[Ss]erver\]|[\d-]+ [\d:]+ I server): (id=\d+ .*)`)
+
+// Groups a Rule regex may capture, named after what they fill in.
+const (
+	GroupId      = "id"
+	GroupTeam    = "team"
+	GroupName    = "name"
+	GroupText    = "text"
+	GroupChannel = "channel"
+	GroupVictim  = "victim"
+	GroupWeapon  = "weapon"
+	GroupTime    = "time"
+	GroupRank    = "rank"
+	GroupMap     = "map"
+	GroupReason  = "reason"
+	GroupResult  = "result"
+	GroupYes     = "yes"
+	GroupNo      = "no"
+)
+
+var groups = []string{
+	GroupId,
+	GroupTeam,
+	GroupName,
+	GroupText,
+	GroupChannel,
+	GroupVictim,
+	GroupWeapon,
+	GroupTime,
+	GroupRank,
+	GroupMap,
+	GroupReason,
+	GroupResult,
+	GroupYes,
+	GroupNo,
+}
+
+// requiredGroups lists what an event of each kind can't do without. A
+// rule template stands in for "text".
+var requiredGroups = map[EventKind][]string{
+	EventChat:           {GroupName, GroupText},
+	EventJoin:           {GroupName},
+	EventLeave:          {GroupName},
+	EventInfo:           {GroupText},
+	EventKill:           {GroupName, GroupVictim},
+	EventFinish:         {GroupName, GroupTime},
+	EventTeamFinish:     {GroupName, GroupTime},
+	EventRecord:         {GroupName, GroupTime},
+	EventPersonalRecord: {GroupTime},
+	EventRank:           {GroupName, GroupTime},
+	EventMap:            {GroupMap},
+	EventVote:           {GroupName, GroupText},
+	EventVoteResult:     {GroupResult},
+}
+
+// Rule turns log lines matching Regex into events of Kind. Named groups
+// (see the Group constants) fill in event fields. Template, if set, is
+// a text/template over the named groups that makes up the event text,
+// e.g. "captured the flag in {{.text}} seconds".
+type Rule struct {
+	Regex    string    `mapstructure:"regex"`
+	Kind     EventKind `mapstructure:"kind"`
+	Template string    `mapstructure:"template"`
+}
+
+// CustomAdapterOpts describes the log format of a server type unknown to
+// the bridge. Rules are tried in order. Status matches entries of
+// "status" output, its first group is parsed like the built-in types do;
+// by default server log lines of the built-in formats are. A custom
+// Status should be anchored to the start of the line, or chat quoting
+// an entry adds a player.
+type CustomAdapterOpts struct {
+	Rules  []Rule `mapstructure:"rules"`
+	Status string `mapstructure:"status"`
+}
+
+type customRule struct {
+	regex    *regexp.Regexp
+	kind     EventKind
+	template *template.Template
+}
+
+type customAdapter struct {
+	rules  []customRule
+	status *regexp.Regexp
+}
+
+func NewCustomAdapter(opts CustomAdapterOpts) (Adapter, error) {
+	if len(opts.Rules) == 0 {
+		return nil, errors.New("econ: adapter has no rules")
+	}
+
+	adapter := customAdapter{status: defaultStatusRegex}
+
+	if opts.Status != "" {
+		status, err := regexp.Compile(opts.Status)
+		if err != nil {
+			return nil, fmt.Errorf("econ: status: %w", err)
+		}
+
+		if status.NumSubexp() == 0 {
+			return nil, errors.New("econ: status: regex has no group for the entry")
+		}
+
+		adapter.status = status
+	}
+
+	for i, x := range opts.Rules {
+		rule, err := newCustomRule(x)
+		if err != nil {
+			return nil, fmt.Errorf("econ: rule %v: %w", i+1, err)
+		}
+
+		adapter.rules = append(adapter.rules, rule)
+	}
+
+	return adapter, nil
+}
+
+func newCustomRule(x Rule) (customRule, error) {
+	required, ok := requiredGroups[x.Kind]
+	if !ok {
+		return customRule{}, fmt.Errorf("unknown event kind %q", x.Kind)
+	}
+
+	regex, err := regexp.Compile(x.Regex)
+	if err != nil {
+		return customRule{}, err
+	}
+
+	names := map[string]bool{}
+	for _, name := range regex.SubexpNames() {
+		if name == "" {
+			continue
+		}
+
+		known := false
+		for _, group := range groups {
+			known = known || group == name
+		}
+
+		if !known {
+			return customRule{}, fmt.Errorf(
+				"unknown group %q, expected one of %v",
+				name,
+				strings.Join(groups, ", "),
+			)
+		}
+
+		names[name] = true
+	}
+
+	rule := customRule{regex: regex, kind: x.Kind}
+
+	if x.Template != "" {
+		rule.template, err = template.New(string(x.Kind)).Option("missingkey=zero").Parse(x.Template)
+		if err != nil {
+			return customRule{}, err
+		}
+		names[GroupText] = true
+	}
+
+	for _, group := range required {
+		if !names[group] {
+			return customRule{}, fmt.Errorf("%v events need a %q group", x.Kind, group)
+		}
+	}
+
+	return rule, nil
+}
+
+func (a customAdapter) Match(bytes []byte) (Event, bool) {
+	for _, rule := range a.rules {
+		match := rule.regex.FindStringSubmatch(string(bytes))
+		if len(match) == 0 {
+			continue
+		}
+
+		return rule.event(bytes, match)
+	}
+
+	return Event{}, false
+}
+
+func (r customRule) event(raw []byte, match []string) (Event, bool) {
+	values := map[string]string{}
+	for i, name := range r.regex.SubexpNames() {
+		if name != "" {
+			values[name] = match[i]
+		}
+	}
+
+	event := NewEvent(r.kind, raw)
+	event.Player = values[GroupName]
+	event.Text = values[GroupText]
+	event.Victim = values[GroupVictim]
+	event.Map = values[GroupMap]
+	event.Reason = values[GroupReason]
+	event.Result = values[GroupResult]
+	event.Rank, _ = strconv.Atoi(values[GroupRank])
+	event.Yes, _ = strconv.Atoi(values[GroupYes])
+	event.No, _ = strconv.Atoi(values[GroupNo])
+
+	if id, err := strconv.Atoi(values[GroupId]); err == nil {
+		event.ClientId = id
+	}
+
+	if team, err := strconv.Atoi(values[GroupTeam]); err == nil {
+		event.Team = team
+	} else if r.kind == EventChat {
+		event.Team = teamAll
+	}
+
+	event.Weapon = values[GroupWeapon]
+	if weapon, err := strconv.Atoi(event.Weapon); err == nil && weapons[weapon] != "" {
+		event.Weapon = weapons[weapon]
+	}
+
+	if text, ok := values[GroupTime]; ok {
+		duration, ok := ParseRaceTime(text)
+		if !ok {
+			return Event{}, false
+		}
+		event.Duration = duration
+	}
+
+	if r.kind == EventChat {
+		event.Channel = customChannel(values[GroupChannel], event.Team)
+	}
+
+	if r.template != nil {
+		var builder strings.Builder
+		if err := r.template.Execute(&builder, values); err != nil {
+			return Event{}, false
+		}
+		event.Text = builder.String()
+	}
+
+	return event, true
+}
+
+// customChannel takes a channel group holding either a channel name or a
+// log category like "teamchat". Anything else is told apart by team, so
+// routes never see a channel they can't filter.
+func customChannel(value string, team int) Channel {
+	channel := Channel(strings.ToLower(value))
+	if slices.Contains(Channels, channel) {
+		return channel
+	}
+
+	return chatChannel(string(channel), team)
+}
+
+func (a customAdapter) Player(bytes []byte) (Player, bool) {
+	match := a.status.FindStringSubmatch(string(bytes))
+	if len(match) == 0 {
+		return Player{}, false
+	}
+
+	return parsePlayer(match[1])
+}
+
+// RegisterAdapter adds a server type, it must be called before any
+// server of that type is started.
+func RegisterAdapter(serverType ServerType, adapter Adapter) error {
+	if _, ok := Adapters[serverType]; ok {
+		return ErrAdapterExists
+	}
+
+	Adapters[serverType] = adapter
+	return nil
+}
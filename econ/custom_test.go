@@ -0,0 +1,57 @@
+package econ
+
+import "testing"
+
+func TestCustomChannel(t *testing.T) {
+	adapter, err := NewCustomAdapter(CustomAdapterOpts{
+		Rules: []Rule{{
+			Regex: `\[(?P<channel>\w+)\]: (?P<id>\d+):(?P<team>-?\d+):(?P<name>.*?): (?P<text>.*)`,
+			Kind:  EventChat,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line string
+		want Channel
+	}{
+		{"[chat]: 0:-2:Alice: hi", ChannelPublic},
+		{"[teamchat]: 0:1:Alice: hi", ChannelTeam},
+		{"[whisper]: 0:1:Alice: hi", ChannelWhisper},
+		{"[spectators]: 0:-1:Alice: hi", ChannelSpectators},
+		{"[Server]: 0:-2:Alice: hi", ChannelServer},
+		{"[bogus]: 0:1:Alice: hi", ChannelTeam},
+		{"[bogus]: 0:-1:Alice: hi", ChannelSpectators},
+	}
+
+	for _, test := range tests {
+		event, ok := adapter.Match([]byte(test.line))
+		if !ok {
+			t.Errorf("Match(%q) failed", test.line)
+			continue
+		}
+
+		if event.Channel != test.want {
+			t.Errorf("Match(%q) channel = %q, want %q", test.line, event.Channel, test.want)
+		}
+	}
+}
+
+func TestCustomDefaultStatus(t *testing.T) {
+	adapter, err := NewCustomAdapter(CustomAdapterOpts{
+		Rules: []Rule{{Regex: `(?P<name>.*) joined`, Kind: EventJoin}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := adapter.Player([]byte("[5f3a1b2c][server]: id=0 addr=1.2.3.4:8303 name='Alice' score=0")); !ok {
+		t.Error("status entry wasn't parsed")
+	}
+
+	if _, ok := adapter.Player([]byte("[5f3a1b2c][chat]: 3:-2:Bob: id=9 addr=1.2.3.4:5 name='FakeAdmin' score=0")); ok {
+		t.Error("chat was parsed as a status entry")
+	}
+}
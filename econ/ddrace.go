@@ -0,0 +1,158 @@
+package econ
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// raceTimePattern matches the time formats understood by ParseRaceTime.
+const raceTimePattern = `\d+ minute\(s\)\s+\d+\.\d+ second\(s\)|\d+\.\d+ second\(s\)|(?:\d+:)?\d+:\d+\.\d+`
+
+var (
+	ddnetKillRegex = regexp.MustCompile(`.* I game: kill killer='(-?\d+):(.*?)' victim='(\d+):(.*)' weapon=(-?\d+) special=\d+`)
+
+	// "name finished in: 1 minute(s) 23.45 second(s)" or, on newer
+	// servers, "name finished in: 01:23.45"
+	ddraceFinishRegex = regexp.MustCompile(`^(.+?) finished in: +(` + raceTimePattern + `)$`)
+	// "New record: 1.23 second(s) better."
+	ddracePersonalRecordRegex = regexp.MustCompile(`^New record: +(` + raceTimePattern + `) better\.$`)
+	// "'name' has set a new map record: 01:23.45"
+	ddraceServerRecordRegex = regexp.MustCompile(`^'(.+)' has set a new (?:map |server )?record: +(` + raceTimePattern + `)$`)
+	// "42. name Time: 01:23.45, requested by other"
+	ddraceRankRegex = regexp.MustCompile(`^(\d+)\. (.+?) Time: (` + raceTimePattern + `)(?:, requested by .+)?$`)
+
+	minutesSecondsRegex = regexp.MustCompile(`^(?:(\d+) minute\(s\)\s+)?(\d+(?:\.\d+)?) second\(s\)$`)
+)
+
+var weapons = map[int]string{
+	-3: "game",
+	-2: "self",
+	-1: "world",
+	0:  "hammer",
+	1:  "gun",
+	2:  "shotgun",
+	3:  "grenade",
+	4:  "laser",
+	5:  "ninja",
+}
+
+func killEvent(raw []byte, match []string) Event {
+	event := NewEvent(EventKill, raw)
+	event.ClientId, _ = strconv.Atoi(match[1])
+	event.Player = match[2]
+	event.Victim = match[4]
+
+	weapon, _ := strconv.Atoi(match[5])
+	event.Weapon = weapons[weapon]
+	if event.Weapon == "" {
+		event.Weapon = strconv.Itoa(weapon)
+	}
+
+	return event
+}
+
+// raceEvent classifies DDRace announcements, reporting false for
+// anything else.
+func raceEvent(raw []byte, text string) (Event, bool) {
+	if match := ddraceRankRegex.FindStringSubmatch(text); len(match) != 0 {
+		duration, ok := ParseRaceTime(match[3])
+		if !ok {
+			return Event{}, false
+		}
+
+		event := NewEvent(EventRank, raw)
+		event.Rank, _ = strconv.Atoi(match[1])
+		event.Player = match[2]
+		event.Duration = duration
+		return event, true
+	}
+
+	if match := ddraceServerRecordRegex.FindStringSubmatch(text); len(match) != 0 {
+		duration, ok := ParseRaceTime(match[2])
+		if !ok {
+			return Event{}, false
+		}
+
+		event := NewEvent(EventRecord, raw)
+		event.Player = match[1]
+		event.Duration = duration
+		return event, true
+	}
+
+	if match := ddracePersonalRecordRegex.FindStringSubmatch(text); len(match) != 0 {
+		duration, ok := ParseRaceTime(match[1])
+		if !ok {
+			return Event{}, false
+		}
+
+		// the line right after the finish, which doesn't repeat the name
+		event := NewEvent(EventPersonalRecord, raw)
+		event.Duration = duration
+		return event, true
+	}
+
+	if match := ddraceFinishRegex.FindStringSubmatch(text); len(match) != 0 {
+		duration, ok := ParseRaceTime(match[2])
+		if !ok {
+			return Event{}, false
+		}
+
+		kind := EventFinish
+		if strings.Contains(match[1], " & ") {
+			// team members are listed as "a & b & c"
+			kind = EventTeamFinish
+		}
+
+		event := NewEvent(kind, raw)
+		event.Player = match[1]
+		event.Duration = duration
+		return event, true
+	}
+
+	return Event{}, false
+}
+
+// ParseRaceTime parses the ways DDNet prints race times: "1 minute(s)
+// 23.45 second(s)", "23.45 second(s)", "01:23.45" and "1:01:23.45".
+func ParseRaceTime(text string) (time.Duration, bool) {
+	text = strings.TrimSpace(text)
+
+	if match := minutesSecondsRegex.FindStringSubmatch(text); len(match) != 0 {
+		minutes, _ := strconv.Atoi(match[1])
+		seconds, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			return 0, false
+		}
+
+		return time.Duration(minutes)*time.Minute + roundSeconds(seconds), true
+	}
+
+	parts := strings.Split(text, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, false
+	}
+
+	duration := roundSeconds(seconds)
+	for i, unit := range []time.Duration{time.Minute, time.Hour}[:len(parts)-1] {
+		value, err := strconv.Atoi(parts[len(parts)-2-i])
+		if err != nil {
+			return 0, false
+		}
+		duration += time.Duration(value) * unit
+	}
+
+	return duration, true
+}
+
+// roundSeconds keeps "23.45" from turning into 23.449999999s.
+func roundSeconds(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds*1000)) * time.Millisecond
+}
@@ -0,0 +1,58 @@
+package econ
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDDNetRaceEvents(t *testing.T) {
+	tests := []struct {
+		line     string
+		kind     EventKind
+		player   string
+		duration time.Duration
+	}{
+		{"2024-01-01 00:00:00 I chat: *** Alice finished in: 01:23.45", EventFinish, "Alice", 83450 * time.Millisecond},
+		{"2024-01-01 00:00:00 I chat: *** Alice finished in: 1 minute(s)  3.45 second(s)", EventFinish, "Alice", 63450 * time.Millisecond},
+		{"2024-01-01 00:00:00 I chat: *** Alice & Bob finished in: 00:12.00", EventTeamFinish, "Alice & Bob", 12 * time.Second},
+		{"2024-01-01 00:00:00 I chat: *** New record:  1.23 second(s) better.", EventPersonalRecord, "", 1230 * time.Millisecond},
+		{"2024-01-01 00:00:00 I chat: *** 'Alice' has set a new map record: 01:23.45", EventRecord, "Alice", 83450 * time.Millisecond},
+		{"2024-01-01 00:00:00 I chat: *** 3. Alice Time: 01:23.45, requested by Bob", EventRank, "Alice", 83450 * time.Millisecond},
+
+		// chat bridged from Telegram, which a player could have typed
+		{"2024-01-01 00:00:00 I chat: *** Bob: Alice finished in: soon", EventInfo, "", 0},
+		{"2024-01-01 00:00:00 I chat: *** Bob: Alice has set a new map record: 00:00.01", EventInfo, "", 0},
+		{"2024-01-01 00:00:00 I chat: *** New record: a lot better", EventInfo, "", 0},
+	}
+
+	for _, test := range tests {
+		event, ok := ddnetAdapter{}.Match([]byte(test.line))
+		if !ok {
+			t.Errorf("Match(%q) failed", test.line)
+			continue
+		}
+
+		if event.Kind != test.kind || event.Player != test.player || event.Duration != test.duration {
+			t.Errorf(
+				"Match(%q) = %v %q %v, want %v %q %v",
+				test.line,
+				event.Kind, event.Player, event.Duration,
+				test.kind, test.player, test.duration,
+			)
+		}
+	}
+}
+
+func TestEcho(t *testing.T) {
+	e := &ECON{}
+	e.addEcho("Bob: Alice finished in: 00:00.01")
+
+	line := []byte("2024-01-01 00:00:00 I chat: *** Bob: Alice finished in: 00:00.01")
+	if !e.Echo(line) {
+		t.Fatal("sent line wasn't recognised")
+	}
+
+	if e.Echo(line) {
+		t.Fatal("sent line was recognised twice")
+	}
+}
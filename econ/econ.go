@@ -1,12 +1,13 @@
 package econ
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,41 +16,89 @@ var (
 	ErrAlreadyDisconnected = errors.New("econ: already disconnected")
 	ErrDisconnected        = errors.New("econ: disconnected")
 	ErrWrongPassword       = errors.New("econ: wrong password")
+	ErrAuthTimeout         = errors.New("econ: no reply to the password")
 )
 
 type ECON struct {
+	mu        sync.Mutex
+	writeMu   sync.Mutex
 	connected bool
 	ip        string
 	port      string
 	password  string
 
-	conn net.Conn
+	conn   net.Conn
+	reader *LineReader
+
+	sayMu     sync.Mutex
+	lastSay   time.Time
+	chatLimit int
+	maxLines  int
+	throttle  time.Duration
+
+	echoMu sync.Mutex
+	echoes []echo
 }
 
+// echo is a line sent with "say", the server logs it back as a "*** line"
+// announcement.
+type echo struct {
+	text string
+	sent time.Time
+}
+
+const (
+	echoTimeout = time.Second * 10
+	maxEchoes   = 64
+)
+
 type ECONOpts struct {
 	Ip       string
 	Port     uint16
 	Password string
+
+	// ChatLimit is the longest chat line in bytes, longer messages are
+	// wrapped. MaxLines caps the lines a single message is wrapped into.
+	// Throttle is the minimal delay between "say" commands, so the
+	// server's spam protection doesn't kick in.
+	ChatLimit int
+	MaxLines  int
+	Throttle  time.Duration
 }
 
 func NewECON(opts ECONOpts) (*ECON, error) {
 	return &ECON{
-		ip:       opts.Ip,
-		password: opts.Password,
-		port:     strconv.Itoa(int(opts.Port)),
+		ip:        opts.Ip,
+		password:  opts.Password,
+		port:      strconv.Itoa(int(opts.Port)),
+		chatLimit: opts.ChatLimit,
+		maxLines:  opts.MaxLines,
+		throttle:  opts.Throttle,
 	}, nil
 }
 
 func (e *ECON) Connected() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	return e.connected
 }
 
 func (e *ECON) Connect() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	if e.connected {
 		return ErrAlreadyConnected
 	}
 
-	conn, err := net.Dial("tcp", net.JoinHostPort(e.ip, e.port))
+	dialer := net.Dialer{
+		Timeout: time.Second * 10,
+		// dead peers are detected by keepalive probes
+		KeepAlive: time.Second * 10,
+	}
+
+	conn, err := dialer.Dial("tcp", net.JoinHostPort(e.ip, e.port))
 	if err != nil {
 		return err
 	}
@@ -69,47 +118,84 @@ func (e *ECON) Connect() error {
 		return err
 	}
 
-	conn.SetReadDeadline(time.Now().Add(time.Second * 2))
-	buf := make([]byte, 1024)
-	n, err := conn.Read(buf)
-	if err != nil && !os.IsTimeout(err) {
+	if err := awaitAuth(conn); err != nil {
 		conn.Close()
 		return err
 	}
-	conn.SetReadDeadline(time.Time{})
-
-	if !strings.Contains(string(buf[:n]), "Authentication successful") {
-		return ErrWrongPassword
-	}
 
 	e.conn = conn
+	e.reader = NewLineReader(conn, DefaultMaxLineLength)
 	e.connected = true
 
 	return nil
 }
 
+// awaitAuth reads the reply to the password. A server that is busy
+// (e.g. starting up) may not answer in time, that's ErrAuthTimeout
+// rather than a wrong password.
+func awaitAuth(conn net.Conn) error {
+	conn.SetReadDeadline(time.Now().Add(time.Second * 2))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var (
+		reply []byte
+		buf   = make([]byte, 1024)
+	)
+
+	for {
+		n, err := conn.Read(buf)
+		reply = append(reply, buf[:n]...)
+
+		switch {
+		case strings.Contains(string(reply), "Authentication successful"):
+			return nil
+		case strings.Contains(string(reply), "Wrong password"):
+			return ErrWrongPassword
+		case err != nil && os.IsTimeout(err):
+			return ErrAuthTimeout
+		case err != nil:
+			return err
+		}
+	}
+}
+
 func (e *ECON) Disconnect() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	if !e.connected {
 		return ErrAlreadyDisconnected
 	}
 
 	err := e.conn.Close()
-	if err != nil {
-		return err
-	}
-
 	e.conn = nil
+	e.reader = nil
 	e.connected = false
 
-	return nil
+	return err
 }
 
-func (e *ECON) Write(buf []byte) error {
+func (e *ECON) getConn() (net.Conn, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	if !e.connected {
-		return ErrDisconnected
+		return nil, ErrDisconnected
+	}
+
+	return e.conn, nil
+}
+
+func (e *ECON) Write(buf []byte) error {
+	conn, err := e.getConn()
+	if err != nil {
+		return err
 	}
 
-	_, err := e.conn.Write(append(buf, '\n'))
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+
+	_, err = conn.Write(append(buf, '\n'))
 	if err != nil {
 		return err
 	}
@@ -117,37 +203,117 @@ func (e *ECON) Write(buf []byte) error {
 	return nil
 }
 
+// Read returns the next log line without its terminator.
 func (e *ECON) Read() ([]byte, error) {
-	// "ping" socket
-	if err := e.Write([]byte{}); err != nil {
-		return []byte{}, err
-	}
+	e.mu.Lock()
+	reader, connected := e.reader, e.connected
+	e.mu.Unlock()
 
-	buffer := make([]byte, 8192)
-	n, err := e.conn.Read(buffer)
-	if err != nil {
-		return []byte{}, err
+	if !connected {
+		return []byte{}, ErrDisconnected
 	}
 
-	return buffer[:n], nil
+	return reader.ReadLine()
+}
+
+// Lines streams log lines until the connection fails or ctx is done. The
+// lines channel is closed first, then exactly one value (nil when ctx
+// finished the stream) is sent on the error channel.
+func (e *ECON) Lines(ctx context.Context) (<-chan []byte, <-chan error) {
+	var (
+		lines = make(chan []byte)
+		errch = make(chan error, 1)
+	)
+
+	go func() {
+		defer close(errch)
+
+		for {
+			line, err := e.Read()
+			if err != nil {
+				close(lines)
+				if ctx.Err() != nil {
+					err = nil
+				}
+				errch <- err
+				return
+			}
+
+			if len(line) == 0 {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				close(lines)
+				errch <- nil
+				return
+			case lines <- line:
+			}
+		}
+	}()
+
+	return lines, errch
 }
 
 func (e *ECON) Message(message string) error {
-	if arr := strings.Split(message, "\n"); len(arr) > 1 {
-		err := e.Write([]byte(fmt.Sprintf("say \"%v\"", arr[0])))
+	e.sayMu.Lock()
+	defer e.sayMu.Unlock()
+
+	for _, line := range Wrap(message, e.chatLimit, e.maxLines) {
+		if wait := e.throttle - time.Since(e.lastSay); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		e.addEcho(line)
+
+		err := e.Write([]byte("say " + QuoteArg(line)))
 		if err != nil {
 			return err
 		}
 
-		for _, x := range arr[1:] {
-			err := e.Write([]byte(fmt.Sprintf("say \"> %v\"", x)))
-			if err != nil {
-				return err
-			}
+		e.lastSay = time.Now()
+	}
+
+	return nil
+}
+
+func (e *ECON) addEcho(line string) {
+	e.echoMu.Lock()
+	defer e.echoMu.Unlock()
+
+	// the server logs what it actually sent, see QuoteArg
+	line = strings.Map(func(r rune) rune {
+		if isControl(r) {
+			return ' '
 		}
+		return r
+	}, line)
+
+	if len(e.echoes) == maxEchoes {
+		e.echoes = e.echoes[1:]
+	}
+	e.echoes = append(e.echoes, echo{text: line, sent: time.Now()})
+}
 
-		return nil
+// Echo reports whether a log line is the server announcing a message sent
+// with Message, so the bridge doesn't parse its own chat as game events.
+// Each sent line is matched once.
+func (e *ECON) Echo(line []byte) bool {
+	e.echoMu.Lock()
+	defer e.echoMu.Unlock()
+
+	for len(e.echoes) != 0 && time.Since(e.echoes[0].sent) > echoTimeout {
+		e.echoes = e.echoes[1:]
+	}
+
+	text := string(line)
+	for i, x := range e.echoes {
+		if strings.HasSuffix(text, "*** "+x.text) {
+			e.echoes = append(e.echoes[:i], e.echoes[i+1:]...)
+			return true
+		}
 	}
 
-	return e.Write([]byte(fmt.Sprintf("say \"%v\"", message)))
+	return false
 }
@@ -0,0 +1,38 @@
+package econ
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestAwaitAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		replies []string
+		want    error
+	}{
+		{"success", []string{"Authentication successful. External console access granted.\n"}, nil},
+		{"split success", []string{"Authentication succ", "essful. External console access granted.\n"}, nil},
+		{"wrong password", []string{"Wrong password 1/3.\n"}, ErrWrongPassword},
+		{"no reply", nil, ErrAuthTimeout},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+
+			go func() {
+				for _, reply := range test.replies {
+					server.Write([]byte(reply))
+				}
+			}()
+
+			if err := awaitAuth(client); !errors.Is(err, test.want) {
+				t.Errorf("awaitAuth() = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package econ
+
+import (
+	"strings"
+	"time"
+)
+
+type EventKind string
+
+const (
+	EventChat  EventKind = "chat"
+	EventJoin  EventKind = "join"
+	EventLeave EventKind = "leave"
+	EventInfo  EventKind = "info"
+
+	// DDRace, see ddnetAdapter
+	EventKill           EventKind = "kill"
+	EventFinish         EventKind = "finish"
+	EventTeamFinish     EventKind = "team_finish"
+	EventRecord         EventKind = "record"
+	EventPersonalRecord EventKind = "personal_record"
+	EventRank           EventKind = "rank"
+
+	EventMap        EventKind = "map"
+	EventVote       EventKind = "vote"
+	EventVoteResult EventKind = "vote_result"
+
+	// produced by the bridge itself rather than parsed from the log
+	EventOnline  EventKind = "online"
+	EventOffline EventKind = "offline"
+)
+
+var EventKinds = []EventKind{
+	EventChat,
+	EventJoin,
+	EventLeave,
+	EventInfo,
+	EventKill,
+	EventFinish,
+	EventTeamFinish,
+	EventRecord,
+	EventPersonalRecord,
+	EventRank,
+	EventMap,
+	EventVote,
+	EventVoteResult,
+	EventOnline,
+	EventOffline,
+}
+
+// Event is a single thing that happened on the server. Fields that don't
+// apply to the kind (or aren't present in the log line) are left zero,
+// except ClientId which is -1 when unknown.
+type Event struct {
+	Kind     EventKind
+	Player   string
+	ClientId int
+	Team     int
+	Text     string
+	Time     time.Time
+	Raw      string
+	// Channel is set for chat and server lines
+	Channel Channel
+
+	// Victim and Weapon of kills. For team finishes Player lists the
+	// whole team
+	Victim string
+	Weapon string
+	// Duration is the race time, or how much a personal record improved
+	Duration time.Duration
+	Rank     int
+
+	// Map that was loaded
+	Map string
+	// Votes have the caller as Player and the description as Text
+	Reason string
+	// Result is "passed" or "failed", with the count if the server
+	// printed it
+	Result string
+	Yes    int
+	No     int
+}
+
+func NewEvent(kind EventKind, raw []byte) Event {
+	return Event{
+		Kind:     kind,
+		ClientId: -1,
+		Time:     time.Now(),
+		Raw:      string(raw),
+	}
+}
+
+// serverMessage classifies "*** 'name' ..." chat announcements.
+func serverMessage(raw []byte, name, text string) Event {
+	var event Event
+
+	switch {
+	case strings.HasPrefix(text, "entered and joined"):
+		event = NewEvent(EventJoin, raw)
+	case strings.HasPrefix(text, "has left the game"):
+		event = NewEvent(EventLeave, raw)
+	default:
+		event = NewEvent(EventInfo, raw)
+		event.Text = text
+	}
+
+	event.Player = name
+	return event
+}
@@ -0,0 +1,84 @@
+package econ
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+const DefaultMaxLineLength = 8192
+
+// LineReader splits an ECON stream into log lines. Lines may arrive split
+// across several TCP reads or several per read, both "\n" and "\r\n" are
+// accepted as terminators. Lines longer than the limit are truncated and
+// the rest of them is discarded.
+type LineReader struct {
+	r   *bufio.Reader
+	max int
+}
+
+func NewLineReader(r io.Reader, max int) *LineReader {
+	if max <= 0 {
+		max = DefaultMaxLineLength
+	}
+
+	return &LineReader{
+		r:   bufio.NewReaderSize(r, max),
+		max: max,
+	}
+}
+
+func (l *LineReader) ReadLine() ([]byte, error) {
+	var (
+		line      []byte
+		truncated bool
+	)
+
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+
+		if room := l.max - len(line); len(chunk) > room {
+			chunk = chunk[:room]
+			truncated = true
+		}
+		line = append(line, chunk...)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		break
+	}
+
+	// a line cut right before its "\n" still ends with "\r"
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+
+	if truncated {
+		return trimRune(line), nil
+	}
+
+	return line, nil
+}
+
+// trimRune drops an incomplete UTF-8 sequence left at the end of a
+// truncated line.
+func trimRune(line []byte) []byte {
+	for i := len(line) - 1; i >= 0 && i >= len(line)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(line[i]) {
+			continue
+		}
+
+		if !utf8.FullRune(line[i:]) {
+			return line[:i]
+		}
+
+		break
+	}
+
+	return line
+}
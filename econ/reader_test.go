@@ -0,0 +1,82 @@
+package econ
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+// chunkReader returns one chunk per Read, like TCP reads.
+type chunkReader struct {
+	chunks []string
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(c.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, c.chunks[0])
+	c.chunks[0] = c.chunks[0][n:]
+	if c.chunks[0] == "" {
+		c.chunks = c.chunks[1:]
+	}
+
+	return n, nil
+}
+
+func TestLineReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		max    int
+		want   []string
+	}{
+		{"single", []string{"abc\n"}, 0, []string{"abc"}},
+		{"split read", []string{"ab", "c\nde", "f\n"}, 0, []string{"abc", "def"}},
+		{"several per read", []string{"a\nb\nc\n"}, 0, []string{"a", "b", "c"}},
+		{"crlf", []string{"a\r\nb\r", "\n"}, 0, []string{"a", "b"}},
+		{"empty line", []string{"\n\r\na\n"}, 0, []string{"", "", "a"}},
+		{"over long", []string{"abcdefgh\nij\n"}, 4, []string{"abcd", "ij"}},
+		{"over long crlf", []string{"abc\r\nde\n"}, 4, []string{"abc", "de"}},
+		{"over long split", []string{"abcdefghijklmnopqrstuvwxyz", "0123456789\nok\n"}, 20, []string{"abcdefghijklmnopqrst", "ok"}},
+		{"over long multibyte", []string{"abcé\n"}, 4, []string{"abc"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := NewLineReader(&chunkReader{chunks: test.chunks}, test.max)
+
+			var got []string
+			for {
+				line, err := reader.ReadLine()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, string(line))
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("lines = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLineReaderOneByte(t *testing.T) {
+	reader := NewLineReader(iotest.OneByteReader(&chunkReader{chunks: []string{"abc\r\ndef\n"}}), 0)
+
+	for _, want := range []string{"abc", "def"} {
+		line, err := reader.ReadLine()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(line) != want {
+			t.Errorf("line = %q, want %q", line, want)
+		}
+	}
+}
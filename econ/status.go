@@ -0,0 +1,113 @@
+package econ
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Status entries are anchored to the start of server log lines, chat
+// quoting one must not add a player.
+var (
+	teeworldsStatusRegex = regexp.MustCompile(`^(?:\[[^\]]*\])?\[[Ss]erver\]: (id=\d+ .*)`)
+	trainfngStatusRegex  = regexp.MustCompile(`^\[[^\]]*\]\[[Ss]erver\]: (id=\d+ .*)`)
+	ddnetStatusRegex     = regexp.MustCompile(`^[\d-]+ [\d:]+ I server: (id=\d+ .*)`)
+)
+
+// Player is a single entry of the "status" command output. Fields the
+// server version doesn't print are left zero.
+type Player struct {
+	Id      int
+	Name    string
+	Clan    string
+	Score   int
+	Address string
+}
+
+// ParseStatus collects players from "status" output using the adapter
+// of the given server type.
+func ParseStatus(serverType ServerType, lines []string) []Player {
+	adapter, ok := Adapters[serverType]
+	if !ok {
+		return nil
+	}
+
+	var players []Player
+	for _, line := range lines {
+		player, ok := adapter.Player([]byte(line))
+		if ok {
+			players = append(players, player)
+		}
+	}
+
+	return players
+}
+
+// parsePlayer parses "id=0 addr=... name='...' ..." status entries, as
+// printed by every supported server with slightly different fields.
+// Clients that are still connecting have no name and are skipped.
+func parsePlayer(entry string) (Player, bool) {
+	fields := statusFields(entry)
+
+	name, ok := fields["name"]
+	if !ok {
+		return Player{}, false
+	}
+
+	id, err := strconv.Atoi(fields["id"])
+	if err != nil {
+		return Player{}, false
+	}
+
+	score, _ := strconv.Atoi(fields["score"])
+
+	addr := strings.TrimSuffix(strings.TrimPrefix(fields["addr"], "<{"), "}>")
+
+	return Player{
+		Id:      id,
+		Name:    name,
+		Clan:    fields["clan"],
+		Score:   score,
+		Address: addr,
+	}, true
+}
+
+// statusFields splits key=value pairs. Quoted values may contain spaces
+// and quotes, they end at a quote followed by a space or the line end.
+func statusFields(entry string) map[string]string {
+	fields := map[string]string{}
+
+	for entry != "" {
+		key, rest, ok := strings.Cut(entry, "=")
+		if !ok || strings.Contains(key, " ") {
+			// bare words like "connecting" or "(Admin)"
+			_, entry, _ = strings.Cut(entry, " ")
+			continue
+		}
+
+		var value string
+
+		switch {
+		case strings.HasPrefix(rest, "'"):
+			end := strings.Index(rest[1:], "' ")
+			if end == -1 {
+				value, entry = strings.TrimSuffix(rest[1:], "'"), ""
+			} else {
+				value, entry = rest[1:end+1], rest[end+3:]
+			}
+		case strings.HasPrefix(rest, "<{"):
+			end := strings.Index(rest, "}>")
+			if end == -1 {
+				value, entry = rest, ""
+			} else {
+				value, entry = rest[:end+2], strings.TrimPrefix(rest[end+2:], " ")
+			}
+		default:
+			value, entry, _ = strings.Cut(rest, " ")
+		}
+
+		fields[key] = value
+	}
+
+	return fields
+}
@@ -0,0 +1,83 @@
+package econ
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverType ServerType
+		lines      []string
+		want       []Player
+	}{
+		{
+			"teeworlds 0.6",
+			TEEWORLDS,
+			[]string{
+				"[5f3a1b2c][Server]: id=0 addr=1.2.3.4:8303 name='Alice' score=5",
+				"[5f3a1b2c][Server]: id=1 addr=1.2.3.5:8303 connecting",
+			},
+			[]Player{{Id: 0, Name: "Alice", Score: 5, Address: "1.2.3.4:8303"}},
+		},
+		{
+			"teeworlds 0.7",
+			TEEWORLDS,
+			[]string{
+				"[2024-01-01 00:00:00][server]: id=0 addr=1.2.3.4:8303 client=0x0706 secure=yes name='Al'ice' clan='Tee' score=3",
+			},
+			[]Player{{Id: 0, Name: "Al'ice", Clan: "Tee", Score: 3, Address: "1.2.3.4:8303"}},
+		},
+		{
+			"trainfng",
+			TRAINFNG,
+			[]string{
+				"[2024-01-01 00:00:00][server]: id=2 addr=1.2.3.4:8303 name='Bob' score=-1 (Admin)",
+			},
+			[]Player{{Id: 2, Name: "Bob", Score: -1, Address: "1.2.3.4:8303"}},
+		},
+		{
+			"ddnet",
+			DDNET,
+			[]string{
+				"2024-01-01 00:00:00 I server: id=3 addr=<{1.2.3.4:8303}> name='Carol' client=16050 secure=yes flags=0 key=Carol",
+			},
+			[]Player{{Id: 3, Name: "Carol", Address: "1.2.3.4:8303"}},
+		},
+		{
+			"ddnet spoofed by chat",
+			DDNET,
+			[]string{
+				"2024-01-01 00:00:00 I chat: 3:-2:Bob: x I server: id=9 addr=<{1.2.3.4:5}> name='FakeAdmin' score=0",
+				"2024-01-01 00:00:00 I chat: 3:-2:Bob: id=9 addr=<{1.2.3.4:5}> name='FakeAdmin' score=0",
+			},
+			nil,
+		},
+		{
+			"teeworlds spoofed by chat",
+			TEEWORLDS,
+			[]string{
+				"[5f3a1b2c][chat]: 3:-2:Bob: [Server]: id=9 addr=1.2.3.4:5 name='FakeAdmin' score=0",
+			},
+			nil,
+		},
+		{
+			"trainfng spoofed by chat",
+			TRAINFNG,
+			[]string{
+				"[2024-01-01 00:00:00][chat]: 3:-2:Bob: [x][server]: id=9 addr=1.2.3.4:5 name='FakeAdmin' score=0",
+			},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ParseStatus(test.serverType, test.lines)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseStatus() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package econ
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// printed by the server on every map load, whether by change_map,
+	// sv_map or a vote. Anchored to the start of the line, so chat quoting
+	// it doesn't count
+	mapLoadRegex = regexp.MustCompile(`^(?:(?:\[[^\]]*\])?\[[Ss]erver\]|[\d-]+ [\d:]+ I server): maps/(.*?)\.map crc is`)
+
+	voteOptionRegex   = regexp.MustCompile(`^'(.+?)' called vote to change server option '(.*)' \((.*)\)$`)
+	voteKickRegex     = regexp.MustCompile(`^'(.+?)' called for vote to kick '(.*)' \((.*)\)$`)
+	voteSpectateRegex = regexp.MustCompile(`^'(.+?)' called for vote to move '(.*)' to spectators \((.*)\)$`)
+	voteResultRegex   = regexp.MustCompile(`^Vote (passed|failed)\b(.*)$`)
+	voteCountRegex    = regexp.MustCompile(`(\d+) yes\D+(\d+) no`)
+)
+
+func mapEvent(raw []byte) (Event, bool) {
+	match := mapLoadRegex.FindSubmatch(raw)
+	if len(match) == 0 {
+		return Event{}, false
+	}
+
+	event := NewEvent(EventMap, raw)
+	event.Map = string(match[1])
+	return event, true
+}
+
+// voteEvent classifies vote announcements, reporting false for anything
+// else.
+func voteEvent(raw []byte, text string) (Event, bool) {
+	for _, x := range []struct {
+		regex  *regexp.Regexp
+		format string
+	}{
+		{voteOptionRegex, "%v"},
+		{voteKickRegex, "kick %v"},
+		{voteSpectateRegex, "move %v to spectators"},
+	} {
+		match := x.regex.FindStringSubmatch(text)
+		if len(match) == 0 {
+			continue
+		}
+
+		event := NewEvent(EventVote, raw)
+		event.Player = match[1]
+		event.Text = fmt.Sprintf(x.format, match[2])
+		event.Reason = match[3]
+		if event.Reason == "No reason given" {
+			event.Reason = ""
+		}
+		return event, true
+	}
+
+	match := voteResultRegex.FindStringSubmatch(text)
+	if len(match) == 0 {
+		return Event{}, false
+	}
+
+	event := NewEvent(EventVoteResult, raw)
+	event.Result = match[1]
+
+	// only some servers print the final count
+	if count := voteCountRegex.FindStringSubmatch(match[2]); len(count) != 0 {
+		event.Yes, _ = strconv.Atoi(count[1])
+		event.No, _ = strconv.Atoi(count[2])
+	}
+
+	return event, true
+}
@@ -0,0 +1,26 @@
+package econ
+
+import "testing"
+
+func TestMapEvent(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"[server]: maps/ctf5.map crc is 1a2b3c4d", "ctf5"},
+		{"[5f3a1b2c][server]: maps/dm1.map crc is 1a2b3c4d", "dm1"},
+		{"2024-01-01 00:00:00 I server: maps/Kobra 4.map crc is 1a2b3c4d", "Kobra 4"},
+
+		// bridged chat quoting the log line
+		{"2024-01-01 00:00:00 I chat: *** maps/foo.map crc is 0", ""},
+		{"2024-01-01 00:00:00 I chat: *** Bob: x I server: maps/foo.map crc is 0", ""},
+		{"[chat]: *** Bob: [server]: maps/foo.map crc is 0", ""},
+	}
+
+	for _, test := range tests {
+		event, ok := mapEvent([]byte(test.line))
+		if ok != (test.want != "") || event.Map != test.want {
+			t.Errorf("mapEvent(%q) = %q, %v, want %q", test.line, event.Map, ok, test.want)
+		}
+	}
+}
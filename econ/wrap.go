@@ -0,0 +1,89 @@
+package econ
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	continuationPrefix = "> "
+	truncatedNotice    = "(message truncated, see Telegram)"
+)
+
+// DefaultChatLimits are the longest chat lines, in bytes, servers of
+// each type display without cutting them.
+var DefaultChatLimits = map[ServerType]int{
+	TEEWORLDS: 127,
+	TRAINFNG:  127,
+	DDNET:     255,
+}
+
+// Wrap splits a message into chat lines of at most limit bytes, breaking
+// on spaces where possible. Lines after the first are marked with "> ".
+// When there are more than maxLines lines, the last one is replaced by a
+// truncation notice. Zero limits disable wrapping or truncation.
+func Wrap(message string, limit, maxLines int) []string {
+	var lines []string
+
+	for i, paragraph := range strings.Split(message, "\n") {
+		if i == 0 {
+			lines = append(lines, wrapLine(paragraph, limit, 0)...)
+		} else {
+			lines = append(lines, wrapLine(paragraph, limit, len(continuationPrefix))...)
+		}
+	}
+
+	for i := range lines[1:] {
+		lines[i+1] = continuationPrefix + lines[i+1]
+	}
+
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = lines[:maxLines]
+		if maxLines == 1 {
+			lines[0] = clamp(truncatedNotice, limit)
+		} else {
+			lines[maxLines-1] = clamp(continuationPrefix+truncatedNotice, limit)
+		}
+	}
+
+	return lines
+}
+
+// clamp cuts the ASCII notice line to limit, so a small limit doesn't have
+// the server cut it instead.
+func clamp(line string, limit int) string {
+	if limit > 0 && len(line) > limit {
+		return line[:limit]
+	}
+
+	return line
+}
+
+// wrapLine splits a single line, reserving room for a prefix on every
+// piece but the first one unless first is non-zero too.
+func wrapLine(line string, limit, first int) []string {
+	var (
+		pieces []string
+		prefix = first
+	)
+
+	for limit > 0 && len(line)+prefix > limit {
+		cut := limit - prefix
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		if cut <= 0 {
+			break
+		}
+
+		if i := strings.LastIndexByte(line[:cut], ' '); i > 0 {
+			cut = i
+		}
+
+		pieces = append(pieces, strings.TrimRight(line[:cut], " "))
+		line = strings.TrimLeft(line[cut:], " ")
+		prefix = len(continuationPrefix)
+	}
+
+	return append(pieces, line)
+}
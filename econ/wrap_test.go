@@ -0,0 +1,58 @@
+package econ
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		limit    int
+		maxLines int
+		want     []string
+	}{
+		{"fits", "hello world", 20, 0, []string{"hello world"}},
+		{"no limit", "hello world", 0, 0, []string{"hello world"}},
+		{"word break", "hello big world", 10, 0, []string{"hello big", "> world"}},
+		{"long word", "abcdefghijkl", 5, 0, []string{"abcde", "> fgh", "> ijk", "> l"}},
+		{"paragraphs", "one\ntwo", 10, 0, []string{"one", "> two"}},
+		{"multibyte", "ééééé", 5, 0, []string{"éé", "> é", "> é", "> é"}},
+		{"emoji", "🔥🔥🔥", 6, 0, []string{"🔥", "> 🔥", "> 🔥"}},
+		{
+			"max lines",
+			"one\ntwo\nthree\nfour",
+			0,
+			3,
+			[]string{"one", "> two", "> " + truncatedNotice},
+		},
+		{"max lines one", "one\ntwo", 0, 1, []string{truncatedNotice}},
+		{
+			"notice over limit",
+			"aaaa bbbb cccc dddd",
+			10,
+			2,
+			[]string{"aaaa bbbb", "> (message"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Wrap(test.message, test.limit, test.maxLines)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("Wrap() = %q, want %q", got, test.want)
+			}
+
+			for _, line := range got {
+				if test.limit > 0 && len(line) > test.limit {
+					t.Errorf("line %q is over the limit", line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %q is not valid UTF-8", line)
+				}
+			}
+		})
+	}
+}
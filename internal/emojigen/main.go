@@ -0,0 +1,309 @@
+// Command emojigen generates the emoji conversion tables of the telegram
+// package from Unicode emoji-test.txt.
+//
+// Every emoji gets a shortcode derived from its CLDR name, e.g.
+// ":thumbs_up:". Styles other than "cldr" add aliases read from
+// <style>-aliases.txt in the data directory, whose lines look like
+//
+//	1F44D ; thumbsup +1
+//
+// (fully-qualified code points, then space separated aliases). The first
+// alias becomes the shortcode emoji are converted to. The github and
+// slack styles are vendored.
+//
+// Shortcodes of the hand-made tables the generator replaced are read from
+// legacy-aliases.txt for every style, unless -legacy=false. They are
+// only converted from, so old in-game spellings like ":Germany:" keep
+// working.
+//
+// To update the tables, replace data/emoji-test.txt with the one from
+// https://unicode.org/Public/emoji/<version>/ and run go generate in the
+// telegram package.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"golang.org/x/text/unicode/norm"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type emoji struct {
+	// fully-qualified form
+	value string
+	// minimally-qualified and unqualified forms
+	variants []string
+	name     string
+	aliases  []string
+	// shortcodes only converted from, see legacy-aliases.txt
+	legacy []string
+}
+
+func main() {
+	var (
+		data   = flag.String("data", "data", "directory with emoji-test.txt and alias files")
+		style  = flag.String("style", "github", "shortcode style: cldr or the name of an alias file")
+		legacy = flag.Bool("legacy", true, "also convert from legacy-aliases.txt shortcodes")
+		out    = flag.String("out", "emojies.go", "output file")
+		pkg    = flag.String("package", "telegram", "package of the output file")
+	)
+	flag.Parse()
+
+	emojies, err := parseEmojiTest(filepath.Join(*data, "emoji-test.txt"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *style != "cldr" {
+		err := parseAliases(filepath.Join(*data, *style+"-aliases.txt"), emojies, false)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *legacy {
+		err := parseAliases(filepath.Join(*data, "legacy-aliases.txt"), emojies, true)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	src, err := generate(*pkg, *style, emojies)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseEmojiTest reads emoji in file order, attaching less qualified
+// forms to the fully-qualified emoji they belong to.
+func parseEmojiTest(path string) ([]*emoji, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		emojies []*emoji
+		byName  = map[string]*emoji{}
+	)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		codes, rest, ok := strings.Cut(line, ";")
+		if !ok {
+			return nil, fmt.Errorf("%v: malformed line %q", path, line)
+		}
+
+		status, comment, ok := strings.Cut(rest, "#")
+		if !ok {
+			return nil, fmt.Errorf("%v: malformed line %q", path, line)
+		}
+
+		value, err := decodeCodePoints(codes)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+
+		// "😀 E1.0 grinning face"
+		fields := strings.SplitN(strings.TrimSpace(comment), " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%v: malformed comment %q", path, comment)
+		}
+		name := fields[2]
+
+		switch strings.TrimSpace(status) {
+		case "fully-qualified", "component":
+			x := &emoji{value: value, name: name}
+			emojies = append(emojies, x)
+			byName[name] = x
+		case "minimally-qualified", "unqualified":
+			x, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("%v: %q has no fully-qualified form", path, name)
+			}
+			x.variants = append(x.variants, value)
+		}
+	}
+
+	return emojies, scanner.Err()
+}
+
+// parseAliases adds the aliases of an alias file to emojies, as legacy
+// ones if legacy is set.
+func parseAliases(path string, emojies []*emoji, legacy bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	byValue := make(map[string]*emoji, len(emojies))
+	for _, x := range emojies {
+		byValue[x.value] = x
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		codes, aliases, ok := strings.Cut(line, ";")
+		if !ok {
+			return fmt.Errorf("%v: malformed line %q", path, line)
+		}
+
+		value, err := decodeCodePoints(codes)
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+
+		x, ok := byValue[value]
+		if !ok {
+			// dropped from Unicode or not yet there
+			log.Printf("%v: skipping unknown emoji %v", path, strings.TrimSpace(codes))
+			continue
+		}
+
+		if legacy {
+			x.legacy = append(x.legacy, strings.Fields(aliases)...)
+		} else {
+			x.aliases = append(x.aliases, strings.Fields(aliases)...)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func decodeCodePoints(codes string) (string, error) {
+	var builder strings.Builder
+
+	for _, code := range strings.Fields(codes) {
+		r, err := strconv.ParseUint(code, 16, 32)
+		if err != nil {
+			return "", fmt.Errorf("bad code point %q", code)
+		}
+		builder.WriteRune(rune(r))
+	}
+
+	return builder.String(), nil
+}
+
+var nameReplacer = strings.NewReplacer(
+	"#", "hash",
+	"*", "asterisk",
+	"&", "and",
+	" ", "_",
+	"-", "_",
+)
+
+// shortcode turns a CLDR name like "flag: Côte d’Ivoire" into
+// "flag_cote_divoire".
+func shortcode(name string) string {
+	name = strings.ToLower(nameReplacer.Replace(norm.NFD.String(name)))
+
+	var builder strings.Builder
+	for _, r := range name {
+		// "Congo - Brazzaville" shouldn't leave a run of underscores
+		if r == '_' && strings.HasSuffix(builder.String(), "_") {
+			continue
+		}
+
+		if r == '_' || r == '+' || (r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))) {
+			builder.WriteRune(r)
+		}
+	}
+
+	return strings.Trim(builder.String(), "_")
+}
+
+func generate(pkg, style string, emojies []*emoji) ([]byte, error) {
+	var (
+		to   = map[string]string{}
+		from = map[string]string{}
+	)
+
+	// aliases of the chosen style win over CLDR names, e.g. GitHub's
+	// ":dog:" is the dog face while CLDR calls the whole dog "dog"
+	for _, x := range emojies {
+		for _, alias := range x.aliases {
+			code := ":" + alias + ":"
+			if prev, ok := to[code]; ok && prev != x.value {
+				log.Printf("%v is used by both %v and %v, keeping the first", code, prev, x.value)
+				continue
+			}
+			to[code] = x.value
+		}
+	}
+
+	for _, x := range emojies {
+		code := ":" + shortcode(x.name) + ":"
+		if _, ok := to[code]; !ok {
+			to[code] = x.value
+		}
+
+		preferred := code
+		if len(x.aliases) != 0 {
+			preferred = ":" + x.aliases[0] + ":"
+		}
+
+		for _, value := range append([]string{x.value}, x.variants...) {
+			from[value] = preferred
+		}
+	}
+
+	// old spellings come last, they never take a shortcode from another
+	// emoji
+	for _, x := range emojies {
+		for _, alias := range x.legacy {
+			code := ":" + alias + ":"
+			if _, ok := to[code]; !ok {
+				to[code] = x.value
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by emojigen -style %v. DO NOT EDIT.\n\n", style)
+	fmt.Fprintf(&buf, "package %v\n\n", pkg)
+
+	writeTable(&buf, "TO_EMOJIES", to)
+	buf.WriteString("\n")
+	writeTable(&buf, "FROM_EMOJIES", from)
+
+	return format.Source(buf.Bytes())
+}
+
+func writeTable(buf *bytes.Buffer, name string, table map[string]string) {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(buf, "var %v = map[string]string{\n", name)
+	for _, key := range keys {
+		fmt.Fprintf(buf, "\t%q: %q,\n", key, table[key])
+	}
+	buf.WriteString("}\n")
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store is a small JSON file backed key-value store for state that has to
+// survive restarts. With an empty path it only keeps state in memory.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data map[string]json.RawMessage
+}
+
+func Open(path string) (*Store, error) {
+	store := &Store{
+		path: path,
+		data: map[string]json.RawMessage{},
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf, &store.data); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Get decodes the value stored under key into v, reporting whether it
+// was present.
+func (s *Store) Get(key string, v any) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, ok := s.data[key]
+	if !ok {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(raw, v)
+}
+
+func (s *Store) Set(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = raw
+	return s.save()
+}
+
+func (s *Store) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data[key]; !ok {
+		return nil
+	}
+
+	delete(s.data, key)
+	return s.save()
+}
+
+// save atomically replaces the state file, callers must hold mu.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	buf, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
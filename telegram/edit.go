@@ -0,0 +1,117 @@
+package telegram
+
+import (
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEditWindow   = time.Minute * 5
+	defaultEditMaxEdits = 3
+)
+
+// EditOpts configures relaying of edited Telegram messages to the game.
+// Only edits made within Window of the original message are relayed, and
+// at most MaxEdits of them per message.
+type EditOpts struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Window   time.Duration `mapstructure:"window"`
+	MaxEdits int           `mapstructure:"max_edits"`
+}
+
+// edits counts relayed edits of recent messages.
+type edits struct {
+	mu     sync.Mutex
+	counts map[messageKey]editCount
+}
+
+type editCount struct {
+	count int
+	sent  time.Time
+}
+
+func newEdits() *edits {
+	return &edits{counts: map[messageKey]editCount{}}
+}
+
+// allow reports whether another edit of the message may be relayed and
+// counts it if so.
+func (e *edits) allow(key messageKey, sent time.Time, window time.Duration, maxEdits int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for k, x := range e.counts {
+		if time.Since(x.sent) > window {
+			delete(e.counts, k)
+		}
+	}
+
+	if time.Since(sent) > window {
+		return false
+	}
+
+	x := e.counts[key]
+	if x.count >= maxEdits {
+		return false
+	}
+
+	e.counts[key] = editCount{count: x.count + 1, sent: sent}
+	return true
+}
+
+func isEditable(msg *gotgbot.Message) bool {
+	return msg.Text != "" || msg.Caption != ""
+}
+
+func (t *Telegram) OnEdit(bot *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EditedMessage
+	if msg == nil || !t.edit.Enabled {
+		return nil
+	}
+
+	r, ok := t.route(ctx)
+	if !ok {
+		return nil
+	}
+
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+
+	// commands aren't bridged in the first place
+	if strings.HasPrefix(text, "/") {
+		return nil
+	}
+
+	window, maxEdits := t.edit.Window, t.edit.MaxEdits
+	if window <= 0 {
+		window = defaultEditWindow
+	}
+	if maxEdits <= 0 {
+		maxEdits = defaultEditMaxEdits
+	}
+
+	sent := time.Unix(msg.Date, 0)
+	key := messageKey{chatId: msg.Chat.Id, messageId: msg.MessageId}
+	if !t.edits.allow(key, sent, window, maxEdits) {
+		return nil
+	}
+
+	data := TemplateData{
+		Server: r.serverName,
+		Name:   ctx.EffectiveSender.Name(),
+		Text:   r.emoji.ToGame(text),
+		Time:   sent,
+	}
+
+	text, ok = t.templates.Game(MessageEdit, data)
+	if ok {
+		r.sendChan <- text
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+)
+
+type EmojiMode string
+
+const (
+	// EmojiPassthrough sends emoji to the game as is, for clients that
+	// render them.
+	EmojiPassthrough EmojiMode = "passthrough"
+	// EmojiShortcode replaces emoji with shortcodes like ":thumbsup:".
+	EmojiShortcode EmojiMode = "shortcode"
+	// EmojiStrip removes emoji.
+	EmojiStrip EmojiMode = "strip"
+	// EmojiAscii replaces emoji with emoticons like ":)" where there is
+	// one, and with shortcodes otherwise.
+	EmojiAscii EmojiMode = "ascii"
+)
+
+var asciiEmojies = map[string]string{
+	"🙂":  ":)",
+	"😀":  ":D",
+	"😃":  ":D",
+	"😄":  ":D",
+	"😁":  ":D",
+	"😆":  "XD",
+	"🤣":  "XD",
+	"😂":  ":'D",
+	"😉":  ";)",
+	"😊":  "^^",
+	"☺️": "^^",
+	"☺":  "^^",
+	"😛":  ":P",
+	"😜":  ";P",
+	"😝":  "XP",
+	"🙁":  ":(",
+	"☹️": ":(",
+	"☹":  ":(",
+	"😞":  ":(",
+	"😢":  ":'(",
+	"😭":  "T_T",
+	"😮":  ":O",
+	"😯":  ":o",
+	"😲":  ":O",
+	"😐":  ":|",
+	"😑":  "-_-",
+	"😕":  ":/",
+	"😠":  ">:(",
+	"😡":  ">:(",
+	"😈":  ">:)",
+	"😇":  "O:)",
+	"😎":  "B)",
+	"😘":  ":*",
+	"😍":  "<3",
+	"❤️": "<3",
+	"❤":  "<3",
+	"💔":  "</3",
+	"👍":  "(y)",
+	"👎":  "(n)",
+}
+
+// EmojiConverter converts emoji between Telegram and the game: game
+// shortcodes always become emoji in Telegram, emoji sent to the game are
+// handled according to the mode.
+type EmojiConverter struct {
+	mode   EmojiMode
+	toTg   *strings.Replacer
+	toGame *strings.Replacer
+}
+
+// NewEmojiConverter builds a converter, merging extra shortcode to emoji
+// aliases into the built-in tables. Extra aliases also win when
+// converting emoji back to shortcodes.
+func NewEmojiConverter(mode EmojiMode, aliases map[string]string) (*EmojiConverter, error) {
+	if mode == "" {
+		mode = EmojiShortcode
+	}
+
+	to := make(map[string]string, len(TO_EMOJIES)+len(aliases))
+	for code, emoji := range TO_EMOJIES {
+		to[code] = emoji
+	}
+
+	from := make(map[string]string, len(FROM_EMOJIES)+len(aliases))
+	for emoji, code := range FROM_EMOJIES {
+		from[emoji] = code
+	}
+
+	for code, emoji := range aliases {
+		if emoji == "" || strings.Trim(code, ":") == "" {
+			return nil, fmt.Errorf("telegram: invalid emoji alias %q: %q", code, emoji)
+		}
+
+		code = ":" + strings.Trim(code, ":") + ":"
+		to[code] = emoji
+		from[emoji] = code
+	}
+
+	converter := &EmojiConverter{
+		mode: mode,
+		toTg: newReplacer(to),
+	}
+
+	switch mode {
+	case EmojiPassthrough:
+	case EmojiShortcode:
+		converter.toGame = newReplacer(from)
+	case EmojiStrip:
+		for emoji := range from {
+			from[emoji] = ""
+		}
+		converter.toGame = newReplacer(from)
+	case EmojiAscii:
+		for emoji, emoticon := range asciiEmojies {
+			from[emoji] = emoticon
+		}
+		converter.toGame = newReplacer(from)
+	default:
+		return nil, fmt.Errorf("telegram: unknown emoji mode %q", mode)
+	}
+
+	return converter, nil
+}
+
+func (c *EmojiConverter) ToTelegram(text string) string {
+	return c.toTg.Replace(text)
+}
+
+func (c *EmojiConverter) ToGame(text string) string {
+	if c.toGame == nil {
+		return text
+	}
+
+	return c.toGame.Replace(text)
+}
@@ -0,0 +1,68 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ParseModeNone       = ""
+	ParseModeHTML       = "HTML"
+	ParseModeMarkdownV2 = "MarkdownV2"
+)
+
+var (
+	htmlEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+	)
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		"_", `\_`,
+		"*", `\*`,
+		"[", `\[`,
+		"]", `\]`,
+		"(", `\(`,
+		")", `\)`,
+		"~", `\~`,
+		"`", "\\`",
+		">", `\>`,
+		"#", `\#`,
+		"+", `\+`,
+		"-", `\-`,
+		"=", `\=`,
+		"|", `\|`,
+		"{", `\{`,
+		"}", `\}`,
+		".", `\.`,
+		"!", `\!`,
+	)
+)
+
+// parseParseMode normalizes a configured parse mode.
+func parseParseMode(mode string) (string, error) {
+	switch strings.ToLower(mode) {
+	case "", "none":
+		return ParseModeNone, nil
+	case "html":
+		return ParseModeHTML, nil
+	case "markdownv2":
+		return ParseModeMarkdownV2, nil
+	}
+
+	return "", fmt.Errorf("templates: unknown parse mode %q", mode)
+}
+
+// Escape makes text safe to embed in a message with the given parse mode,
+// so names and messages from the game can't add formatting or links.
+func Escape(parseMode, text string) string {
+	switch parseMode {
+	case ParseModeHTML:
+		return htmlEscaper.Replace(text)
+	case ParseModeMarkdownV2:
+		return markdownEscaper.Replace(text)
+	}
+
+	return text
+}
@@ -0,0 +1,116 @@
+package telegram
+
+import (
+	"fmt"
+	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"strings"
+	"time"
+)
+
+func FormatPlayers(players []econ.Player, showAddress bool) string {
+	if len(players) == 0 {
+		return "No players online"
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Players online: %v", len(players))
+
+	for _, player := range players {
+		fmt.Fprintf(&builder, "\n%v. %v", player.Id, player.Name)
+
+		if player.Clan != "" {
+			fmt.Fprintf(&builder, " [%v]", player.Clan)
+		}
+		if player.Score != 0 {
+			fmt.Fprintf(&builder, " (%v)", player.Score)
+		}
+		if showAddress && player.Address != "" {
+			fmt.Fprintf(&builder, " %v", player.Address)
+		}
+	}
+
+	return builder.String()
+}
+
+func FormatStatus(serverName, mapName string, players []econ.Player, online bool) string {
+	var builder strings.Builder
+
+	if serverName != "" {
+		builder.WriteString(serverName + "\n")
+	}
+
+	if !online {
+		builder.WriteString("Server is offline")
+		return builder.String()
+	}
+
+	fmt.Fprintf(&builder, "Map: %v\n", mapName)
+	builder.WriteString(FormatPlayers(players, false))
+
+	return builder.String()
+}
+
+// truncate cuts text to at most limit runes, marking the cut with an
+// ellipsis.
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit-1]) + "…"
+}
+
+// utf16Len is the length of text as Telegram counts it, in UTF-16 code
+// units, so most emoji count twice.
+func utf16Len(text string) int {
+	n := 0
+	for _, r := range text {
+		n += runeUnits(r)
+	}
+
+	return n
+}
+
+// runeUnits is the number of UTF-16 units r is encoded with.
+func runeUnits(r rune) int {
+	if r > 0xFFFF {
+		return 2
+	}
+
+	return 1
+}
+
+// truncateMessage shortens text to fit a Telegram message.
+func truncateMessage(text string) string {
+	if utf16Len(text) <= messageLimit {
+		return text
+	}
+
+	size := 0
+	for i, r := range text {
+		// room for the ellipsis
+		if size+runeUnits(r) > messageLimit-1 {
+			return text[:i] + "…"
+		}
+		size += runeUnits(r)
+	}
+
+	return text
+}
+
+// FormatRaceTime formats race times the way DDNet does, as mm:ss.mmm
+// with hours in front when needed.
+func FormatRaceTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	if ms < 0 {
+		ms = -ms
+	}
+
+	hours, minutes, seconds := ms/3600000, ms/60000%60, ms/1000%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d.%03d", hours, minutes, seconds, ms%1000)
+	}
+
+	return fmt.Sprintf("%02d:%02d.%03d", minutes, seconds, ms%1000)
+}
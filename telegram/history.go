@@ -0,0 +1,73 @@
+package telegram
+
+import (
+	"sync"
+	"time"
+)
+
+const historySize = 1000
+
+type messageKey struct {
+	chatId    int64
+	messageId int64
+}
+
+// bridgedMessage remembers what a Telegram message sent by the bridge
+// was made of, or for messages sent to the game, who wrote them.
+type bridgedMessage struct {
+	// Sender is the Telegram user whose message was sent to the game,
+	// empty for messages from the game
+	Sender string
+	// Player wrote the game chat lines, empty if there were several
+	// authors or it wasn't chat
+	Player string
+	// Text is the plain game text, without template markup, or the line
+	// sent to the game
+	Text string
+	Time time.Time
+}
+
+// history keeps the most recent bridged messages, forgetting the oldest
+// ones.
+type history struct {
+	mu    sync.Mutex
+	keys  []messageKey
+	next  int
+	items map[messageKey]bridgedMessage
+}
+
+func newHistory(size int) *history {
+	return &history{
+		keys:  make([]messageKey, size),
+		items: make(map[messageKey]bridgedMessage, size),
+	}
+}
+
+func (h *history) add(key messageKey, msg bridgedMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.items[key]; !ok {
+		delete(h.items, h.keys[h.next])
+		h.keys[h.next] = key
+		h.next = (h.next + 1) % len(h.keys)
+	}
+
+	h.items[key] = msg
+}
+
+func (h *history) get(key messageKey) (bridgedMessage, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	msg, ok := h.items[key]
+	return msg, ok
+}
+
+func (h *history) remove(key messageKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the ring slot is reused once it comes around
+	delete(h.items, key)
+}
@@ -0,0 +1,183 @@
+package telegram
+
+import (
+	"fmt"
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"strings"
+)
+
+// Kinds of Telegram media, see MediaOpts.
+const (
+	MediaSticker   = "sticker"
+	MediaPhoto     = "photo"
+	MediaVideo     = "video"
+	MediaAnimation = "animation"
+	MediaVideoNote = "video_note"
+	MediaVoice     = "voice"
+	MediaAudio     = "audio"
+	MediaDocument  = "document"
+	MediaPoll      = "poll"
+	MediaLocation  = "location"
+	MediaContact   = "contact"
+	MediaDice      = "dice"
+	MediaForward   = "forward"
+)
+
+var mediaKinds = []string{
+	MediaSticker,
+	MediaPhoto,
+	MediaVideo,
+	MediaAnimation,
+	MediaVideoNote,
+	MediaVoice,
+	MediaAudio,
+	MediaDocument,
+	MediaPoll,
+	MediaLocation,
+	MediaContact,
+	MediaDice,
+	MediaForward,
+}
+
+// MediaOpts switches media kinds on and off, every kind is bridged unless
+// set to false. Messages of a disabled kind aren't sent to the game,
+// except for "forward" which only drops the forwarded from label.
+type MediaOpts map[string]bool
+
+func (o MediaOpts) validate() error {
+	for kind := range o {
+		known := false
+		for _, x := range mediaKinds {
+			known = known || x == kind
+		}
+
+		if !known {
+			return fmt.Errorf(
+				"telegram: unknown media kind %q, expected one of %v",
+				kind,
+				strings.Join(mediaKinds, ", "),
+			)
+		}
+	}
+
+	return nil
+}
+
+func (o MediaOpts) enabled(kind string) bool {
+	enabled, ok := o[kind]
+	return !ok || enabled
+}
+
+// describeMedia labels the media of a message for the game, e.g.
+// "[VOICE 0:05]". It reports false if the kind is disabled.
+func describeMedia(r *route, msg *gotgbot.Message, opts MediaOpts) (string, bool) {
+	var kind, label string
+
+	switch {
+	case msg.Sticker != nil:
+		kind, label = MediaSticker, r.emoji.ToGame(msg.Sticker.Emoji)
+		if label == "" {
+			label = "[STICKER]"
+		}
+	case len(msg.Photo) != 0:
+		kind, label = MediaPhoto, "[PHOTO]"
+	case msg.Animation != nil:
+		kind, label = MediaAnimation, "[GIF "+formatDuration(msg.Animation.Duration)+"]"
+	case msg.Video != nil:
+		kind, label = MediaVideo, "[VIDEO "+formatDuration(msg.Video.Duration)+"]"
+	case msg.VideoNote != nil:
+		kind, label = MediaVideoNote, "[VIDEO MESSAGE "+formatDuration(msg.VideoNote.Duration)+"]"
+	case msg.Voice != nil:
+		kind, label = MediaVoice, "[VOICE "+formatDuration(msg.Voice.Duration)+"]"
+	case msg.Audio != nil:
+		kind, label = MediaAudio, "[AUDIO "+formatAudio(msg.Audio)+"]"
+	case msg.Document != nil:
+		kind, label = MediaDocument, "[FILE "+formatDocument(msg.Document)+"]"
+	case msg.Poll != nil:
+		kind, label = MediaPoll, "[POLL "+formatPoll(r, msg.Poll)+"]"
+	case msg.Venue != nil:
+		kind, label = MediaLocation, "[LOCATION "+msg.Venue.Title+", "+msg.Venue.Address+"]"
+	case msg.Location != nil:
+		kind, label = MediaLocation, fmt.Sprintf("[LOCATION %.5f, %.5f]", msg.Location.Latitude, msg.Location.Longitude)
+	case msg.Contact != nil:
+		// the phone number isn't something to post on a public server
+		name := strings.TrimSpace(msg.Contact.FirstName + " " + msg.Contact.LastName)
+		kind, label = MediaContact, "[CONTACT "+name+"]"
+	case msg.Dice != nil:
+		kind, label = MediaDice, fmt.Sprintf("[%v %v]", r.emoji.ToGame(msg.Dice.Emoji), msg.Dice.Value)
+	default:
+		return "[MEDIA]", true
+	}
+
+	return label, opts.enabled(kind)
+}
+
+// forwardOrigin names where a forwarded message comes from.
+func forwardOrigin(msg *gotgbot.Message, opts MediaOpts) string {
+	if !opts.enabled(MediaForward) || msg.IsAutomaticForward {
+		return ""
+	}
+
+	switch {
+	case msg.ForwardFrom != nil:
+		return strings.TrimSpace(msg.ForwardFrom.FirstName + " " + msg.ForwardFrom.LastName)
+	case msg.ForwardFromChat != nil:
+		return msg.ForwardFromChat.Title
+	default:
+		return msg.ForwardSenderName
+	}
+}
+
+func formatDuration(seconds int64) string {
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+func formatAudio(audio *gotgbot.Audio) string {
+	name := audio.Title
+	if audio.Performer != "" && name != "" {
+		name = audio.Performer + " - " + name
+	}
+	if name == "" {
+		name = audio.FileName
+	}
+
+	return strings.TrimSpace(name + " " + formatDuration(audio.Duration))
+}
+
+func formatDocument(document *gotgbot.Document) string {
+	switch {
+	case document.FileSize == 0:
+		return document.FileName
+	case document.FileName == "":
+		return formatSize(document.FileSize)
+	default:
+		return document.FileName + ", " + formatSize(document.FileSize)
+	}
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value, prefix := float64(size)/unit, 0
+	for value >= unit && prefix < 3 {
+		value /= unit
+		prefix++
+	}
+
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[prefix])
+}
+
+func formatPoll(r *route, poll *gotgbot.Poll) string {
+	var builder strings.Builder
+	builder.WriteString(r.emoji.ToGame(poll.Question))
+
+	for i, option := range poll.Options {
+		fmt.Fprintf(&builder, " %d) %v", i+1, r.emoji.ToGame(option.Text))
+	}
+
+	return builder.String()
+}
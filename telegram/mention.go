@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sendersSize = 200
+
+var mentionRegex = regexp.MustCompile(`@([^\s,.!?:;]+)`)
+
+// MentionOpts configures replies to Telegram users addressed in game, as
+// in "@Name hi" or "Name: hi".
+type MentionOpts struct {
+	Enabled bool `mapstructure:"mentions"`
+	// MaxAge is how long after their last message a user can be
+	// addressed, zero means forever
+	MaxAge time.Duration `mapstructure:"mention_age"`
+}
+
+// sender is the latest bridged message of a Telegram user.
+type sender struct {
+	messageId int64
+	time      time.Time
+}
+
+// senders tracks recent Telegram senders of one thread by the names game
+// players may address them with.
+type senders struct {
+	mu    sync.Mutex
+	names map[string]sender
+}
+
+func newSenders() *senders {
+	return &senders{names: map[string]sender{}}
+}
+
+func (s *senders) add(names []string, messageId int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			s.names[strings.ToLower(name)] = sender{messageId: messageId, time: now}
+		}
+	}
+
+	for len(s.names) > sendersSize {
+		// forget the least recent sender
+		var oldest string
+		for name, x := range s.names {
+			if oldest == "" || x.time.Before(s.names[oldest].time) {
+				oldest = name
+			}
+		}
+		delete(s.names, oldest)
+	}
+}
+
+func (s *senders) find(name string, maxAge time.Duration) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	x, ok := s.names[strings.ToLower(name)]
+	if !ok || (maxAge > 0 && time.Since(x.time) > maxAge) {
+		return 0, false
+	}
+
+	return x.messageId, true
+}
+
+// addressee finds the latest message of the Telegram user a game chat
+// line is aimed at, either by a "Name: " prefix or an @mention.
+func (s *senders) addressee(text string, maxAge time.Duration) (int64, bool) {
+	if name, _, ok := strings.Cut(text, ": "); ok {
+		if messageId, ok := s.find(name, maxAge); ok {
+			return messageId, true
+		}
+	}
+
+	for _, match := range mentionRegex.FindAllStringSubmatch(text, -1) {
+		if messageId, ok := s.find(match[1], maxAge); ok {
+			return messageId, true
+		}
+	}
+
+	return 0, false
+}
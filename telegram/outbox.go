@@ -0,0 +1,315 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOutboxWindow      = time.Second
+	defaultOutboxMinInterval = time.Second * 3
+	defaultOutboxRetries     = 5
+
+	// game lines kept while Telegram is rate limiting us, oldest are
+	// dropped first
+	outboxCapacity = 1000
+)
+
+var retryAfterRegex = regexp.MustCompile(`retry after (\d+)`)
+
+// OutboxOpts configures delivery of game events to Telegram. Lines that
+// arrive within Window of each other are sent as one message, and no
+// chat gets more than one message per MinInterval.
+type OutboxOpts struct {
+	Window      time.Duration `mapstructure:"window"`
+	MinInterval time.Duration `mapstructure:"min_interval"`
+	Retries     int           `mapstructure:"retries"`
+}
+
+type outgoing struct {
+	text string
+	// chat author and plain game text, remembered in history
+	player string
+	raw    string
+	// message of the Telegram user a player answers, such lines are
+	// never merged with others
+	replyTo int64
+	// vote announcements get buttons and aren't merged either, the
+	// result of a vote removes them
+	ballot      bool
+	closeBallot bool
+}
+
+func (x outgoing) alone() bool {
+	return x.replyTo != 0 || x.ballot
+}
+
+// outbox buffers lines for one thread until they are sent.
+type outbox struct {
+	mu      sync.Mutex
+	pending []outgoing
+	signal  chan struct{}
+}
+
+func newOutbox() *outbox {
+	return &outbox{signal: make(chan struct{}, 1)}
+}
+
+func (o *outbox) push(x outgoing) {
+	o.mu.Lock()
+	o.pending = append(o.pending, x)
+	if dropped := len(o.pending) - outboxCapacity; dropped > 0 {
+		o.pending = o.pending[dropped:]
+		slog.Warn("Outbox is full, dropping messages", slog.Int("dropped", dropped))
+	}
+	o.mu.Unlock()
+
+	select {
+	case o.signal <- struct{}{}:
+	default:
+	}
+}
+
+// take pops as many pending lines as fit into a single message, by
+// Telegram's count of UTF-16 units.
+func (o *outbox) take() (outgoing, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.pending) == 0 {
+		return outgoing{}, false
+	}
+
+	var (
+		first = o.pending[0]
+		lines = []string{truncateMessage(first.text)}
+		raws  = []string{first.raw}
+		size  = utf16Len(lines[0])
+		n     = 1
+	)
+
+	for ; n < len(o.pending) && !first.alone(); n++ {
+		if o.pending[n].alone() {
+			break
+		}
+
+		next := utf16Len(o.pending[n].text) + 1
+		if size+next > messageLimit {
+			break
+		}
+
+		lines = append(lines, o.pending[n].text)
+		raws = append(raws, o.pending[n].raw)
+		size += next
+
+		if o.pending[n].player != first.player {
+			first.player = ""
+		}
+		first.closeBallot = first.closeBallot || o.pending[n].closeBallot
+	}
+
+	o.pending = o.pending[n:]
+	return outgoing{
+		text:        strings.Join(lines, "\n"),
+		player:      first.player,
+		raw:         strings.Join(raws, "\n"),
+		replyTo:     first.replyTo,
+		ballot:      first.ballot,
+		closeBallot: first.closeBallot,
+	}, true
+}
+
+// limiter spaces out messages sent to the same chat.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[int64]time.Time
+}
+
+func newLimiter(interval time.Duration) *limiter {
+	return &limiter{
+		interval: interval,
+		next:     map[int64]time.Time{},
+	}
+}
+
+// wait blocks until a message may be sent to the chat and reserves that
+// slot.
+func (l *limiter) wait(ctx context.Context, chatId int64) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next[chatId]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[chatId] = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	return sleep(ctx, time.Until(slot))
+}
+
+// delay pushes back every message to the chat, used when Telegram asks
+// us to slow down.
+func (l *limiter) delay(chatId int64, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); l.next[chatId].Before(until) {
+		l.next[chatId] = until
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// deliver sends the outbox of one thread until ctx is done.
+func (t *Telegram) deliver(ctx context.Context, r *route) {
+	window := t.outbox.Window
+	if window <= 0 {
+		window = defaultOutboxWindow
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.outbox.signal:
+		}
+
+		// let a burst of lines gather
+		if sleep(ctx, window) != nil {
+			return
+		}
+
+		for {
+			x, ok := r.outbox.take()
+			if !ok {
+				break
+			}
+
+			msg, err := t.send(ctx, r, x)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+
+				slog.Error(
+					"Failed to send message to Telegram, dropping it!",
+					slog.String("server", r.serverName),
+					slog.String(
+						"err",
+						err.Error(),
+					),
+				)
+				continue
+			}
+
+			t.history.add(messageKey{chatId: r.chatId, messageId: msg.MessageId}, bridgedMessage{
+				Player: x.player,
+				Text:   x.raw,
+				Time:   time.Now(),
+			})
+
+			switch {
+			case x.ballot:
+				t.closeBallot(r, r.ballot.open(msg.MessageId))
+			case x.closeBallot:
+				t.closeBallot(r, r.ballot.close())
+			}
+		}
+	}
+}
+
+// send posts a message, retrying when rate limited or on transient
+// failures.
+func (t *Telegram) send(ctx context.Context, r *route, x outgoing) (*gotgbot.Message, error) {
+	retries := t.outbox.Retries
+	if retries <= 0 {
+		retries = defaultOutboxRetries
+	}
+
+	var (
+		err       error
+		parseMode = t.templates.ParseMode()
+	)
+
+	for attempt := 0; attempt <= retries; attempt++ {
+		if err := t.limiter.wait(ctx, r.chatId); err != nil {
+			return nil, err
+		}
+
+		opts := &gotgbot.SendMessageOpts{
+			MessageThreadId:          r.threadId,
+			ParseMode:                parseMode,
+			ReplyToMessageId:         x.replyTo,
+			AllowSendingWithoutReply: true,
+		}
+		if x.ballot {
+			opts.ReplyMarkup = voteKeyboard
+		}
+
+		var msg *gotgbot.Message
+		msg, err = t.bot.SendMessage(r.chatId, x.text, opts)
+		if err == nil {
+			return msg, nil
+		}
+
+		if parseMode != ParseModeNone && isTelegramError(err, "can't parse entities") {
+			// most likely a message cut at the length limit, better
+			// show the markup than lose the message
+			slog.Warn(
+				"Telegram rejected message markup, sending as plain text",
+				slog.String("server", r.serverName),
+				slog.String("err", err.Error()),
+			)
+			parseMode = ParseModeNone
+			continue
+		}
+
+		var tgErr *gotgbot.TelegramError
+		if !errors.As(err, &tgErr) || tgErr.Code >= 500 {
+			// network or server failure, try again after a while
+			t.limiter.delay(r.chatId, time.Second*time.Duration(attempt+1))
+			continue
+		}
+
+		if tgErr.Code != 429 {
+			return nil, err
+		}
+
+		retryAfter := time.Second
+		if match := retryAfterRegex.FindStringSubmatch(tgErr.Description); len(match) != 0 {
+			seconds, _ := strconv.Atoi(match[1])
+			retryAfter = time.Second * time.Duration(seconds)
+		}
+
+		slog.Warn(
+			"Rate limited by Telegram",
+			slog.String("server", r.serverName),
+			slog.Duration("retry_after", retryAfter),
+		)
+		t.limiter.delay(r.chatId, retryAfter)
+	}
+
+	return nil, err
+}
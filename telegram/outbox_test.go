@@ -0,0 +1,41 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutboxTakeCountsUTF16(t *testing.T) {
+	o := newOutbox()
+
+	// 2000 emoji are 4000 UTF-16 units, two of them don't fit together
+	line := strings.Repeat("🔥", 2000)
+	o.push(outgoing{text: line})
+	o.push(outgoing{text: line})
+
+	for i := 0; i < 2; i++ {
+		x, ok := o.take()
+		if !ok {
+			t.Fatalf("take %v: nothing pending", i+1)
+		}
+
+		if size := utf16Len(x.text); size > messageLimit {
+			t.Errorf("take %v: message is %v units long", i+1, size)
+		}
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	text := truncateMessage(strings.Repeat("🔥", messageLimit))
+	if size := utf16Len(text); size > messageLimit {
+		t.Errorf("truncated message is %v units long", size)
+	}
+
+	if !strings.HasSuffix(text, "…") {
+		t.Error("truncated message has no ellipsis")
+	}
+
+	if short := "gg 🔥"; truncateMessage(short) != short {
+		t.Error("short message was changed")
+	}
+}
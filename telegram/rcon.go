@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"strings"
+)
+
+var (
+	ErrRconForbidden = errors.New("telegram: you are not allowed to use rcon")
+	ErrRconEmpty     = errors.New("telegram: usage: /rcon <command>")
+)
+
+// RconUser grants a Telegram user access to /rcon. An empty Allow list
+// permits every command not listed in Deny.
+type RconUser struct {
+	Id    int64    `mapstructure:"id"`
+	Allow []string `mapstructure:"allow"`
+	Deny  []string `mapstructure:"deny"`
+}
+
+type RconPolicy struct {
+	users map[int64]RconUser
+}
+
+func NewRconPolicy(users []RconUser) RconPolicy {
+	policy := RconPolicy{users: make(map[int64]RconUser, len(users))}
+	for _, user := range users {
+		policy.users[user.Id] = user
+	}
+
+	return policy
+}
+
+// Check reports whether the user may run every command in line.
+func (p RconPolicy) Check(userId int64, line string) error {
+	user, ok := p.users[userId]
+	if !ok {
+		return ErrRconForbidden
+	}
+
+	if econ.HasControl(line) {
+		return econ.ErrControlCharacter
+	}
+
+	commands := econ.SplitCommands(line)
+	if len(commands) == 0 {
+		return ErrRconEmpty
+	}
+
+	for _, command := range commands {
+		name := econ.CommandName(command)
+
+		if contains(user.Deny, name) || (len(user.Allow) != 0 && !contains(user.Allow, name)) {
+			return fmt.Errorf("telegram: command %q is not allowed", name)
+		}
+	}
+
+	return nil
+}
+
+func contains(list []string, name string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, name) {
+			return true
+		}
+	}
+
+	return false
+}
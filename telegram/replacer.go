@@ -0,0 +1,35 @@
+package telegram
+
+//go:generate go run ../internal/emojigen -data ../internal/emojigen/data -style github -out emojies.go
+
+import (
+	"sort"
+	"strings"
+)
+
+// newReplacer builds a single pass replacer for a conversion table.
+// strings.Replacer matches against a trie and prefers earlier pairs, so
+// ordering keys longest first makes it pick the longest match at every
+// position, e.g. a skin tone sequence over its base emoji. Ties are
+// broken alphabetically to keep the output stable.
+func newReplacer(table map[string]string) *strings.Replacer {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	pairs := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		pairs = append(pairs, key, table[key])
+	}
+
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,96 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	gameSample     = "gg :thumbsup: see you on :fire: map :waving_hand_medium_skin_tone: :100:"
+	telegramSample = "gg 👍 see you on 🔥 map 👋🏽 💯 👍🏽"
+)
+
+// mapReplace is how the tables used to be applied, one ReplaceAll per
+// entry in map order.
+func mapReplace(table map[string]string, text string) string {
+	for orig, replace := range table {
+		text = strings.ReplaceAll(text, orig, replace)
+	}
+
+	return text
+}
+
+func BenchmarkReplaceToEmoji(b *testing.B) {
+	b.Run("map", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mapReplace(TO_EMOJIES, gameSample)
+		}
+	})
+
+	b.Run("replacer", func(b *testing.B) {
+		replacer := newReplacer(TO_EMOJIES)
+		// the trie is built on first use
+		replacer.Replace(gameSample)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			replacer.Replace(gameSample)
+		}
+	})
+}
+
+func BenchmarkReplaceFromEmoji(b *testing.B) {
+	b.Run("map", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mapReplace(FROM_EMOJIES, telegramSample)
+		}
+	})
+
+	b.Run("replacer", func(b *testing.B) {
+		replacer := newReplacer(FROM_EMOJIES)
+		// the trie is built on first use
+		replacer.Replace(telegramSample)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			replacer.Replace(telegramSample)
+		}
+	})
+}
+
+func TestReplacerPrefersLongestMatch(t *testing.T) {
+	tests := []struct {
+		table map[string]string
+		input string
+	}{
+		{FROM_EMOJIES, "👍🏽"},
+		{FROM_EMOJIES, "👋🏽"},
+		{TO_EMOJIES, ":thumbs_up_medium_skin_tone:"},
+	}
+
+	for _, test := range tests {
+		want, ok := test.table[test.input]
+		if !ok {
+			t.Fatalf("%q isn't in the table", test.input)
+		}
+
+		// map order differs between builds, the result must not
+		for i := 0; i < 10; i++ {
+			if got := newReplacer(test.table).Replace(test.input); got != want {
+				t.Fatalf("Replace(%q) = %q, want %q", test.input, got, want)
+			}
+		}
+	}
+}
+
+func TestLegacyShortcodes(t *testing.T) {
+	for code, want := range map[string]string{
+		":Germany:":                         "🇩🇪",
+		":breast-feeding:":                  "🤱",
+		":thumbs_up_medium-dark_skin_tone:": "👍🏾",
+	} {
+		if got := TO_EMOJIES[code]; got != want {
+			t.Errorf("TO_EMOJIES[%q] = %q, want %q", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package telegram
+
+import (
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"log/slog"
+	"time"
+)
+
+// Retract deletes a Telegram message that was sent to the game and tells
+// the players it was removed. Bots can't see deletions, so moderators
+// reply /retract to the message instead of deleting it themselves.
+func (t *Telegram) Retract(bot *gotgbot.Bot, ctx *ext.Context) error {
+	r, key, ok := t.moderatedReply(bot, ctx)
+	if !ok {
+		return nil
+	}
+
+	bridged, ok := t.history.get(key)
+	if !ok || bridged.Sender == "" {
+		_, err := ctx.EffectiveMessage.Reply(bot, "This message wasn't sent to the game.", nil)
+		return err
+	}
+
+	if err := t.deleteBridged(bot, ctx, key); err != nil {
+		return err
+	}
+
+	slog.Info(
+		"Retracted message",
+		slog.String("server", r.serverName),
+		slog.Int64("user", ctx.EffectiveSender.Id()),
+		slog.String("sender", bridged.Sender),
+	)
+
+	text, ok := t.templates.Game(MessageRetract, TemplateData{
+		Server: r.serverName,
+		Name:   bridged.Sender,
+		Time:   time.Now(),
+	})
+	if ok {
+		r.sendChan <- text
+	}
+
+	return nil
+}
+
+// Del deletes a message the bridge posted from the game.
+func (t *Telegram) Del(bot *gotgbot.Bot, ctx *ext.Context) error {
+	_, key, ok := t.moderatedReply(bot, ctx)
+	if !ok {
+		return nil
+	}
+
+	bridged, ok := t.history.get(key)
+	if !ok || bridged.Sender != "" {
+		_, err := ctx.EffectiveMessage.Reply(bot, "This message didn't come from the game.", nil)
+		return err
+	}
+
+	return t.deleteBridged(bot, ctx, key)
+}
+
+// moderatedReply finds the message a moderator command replies to,
+// reporting false if the command should be ignored.
+func (t *Telegram) moderatedReply(bot *gotgbot.Bot, ctx *ext.Context) (*route, messageKey, bool) {
+	r, ok := t.route(ctx)
+	if !ok || ctx.EffectiveSender == nil {
+		return nil, messageKey{}, false
+	}
+
+	msg := ctx.EffectiveMessage
+	reply := msg.ReplyToMessage
+	if reply == nil || reply.MessageId == msg.MessageThreadId || reply.ForumTopicCreated != nil {
+		_, err := msg.Reply(bot, "Reply to a bridged message with this command.", nil)
+		if err != nil {
+			slog.Error("Failed to reply to command!", slog.String("err", err.Error()))
+		}
+		return nil, messageKey{}, false
+	}
+
+	if !t.isModerator(bot, ctx) {
+		_, err := msg.Reply(bot, "Only chat administrators can do this.", nil)
+		if err != nil {
+			slog.Error("Failed to reply to command!", slog.String("err", err.Error()))
+		}
+		return nil, messageKey{}, false
+	}
+
+	return r, messageKey{chatId: reply.Chat.Id, messageId: reply.MessageId}, true
+}
+
+func (t *Telegram) isModerator(bot *gotgbot.Bot, ctx *ext.Context) bool {
+	if ctx.EffectiveSender.IsAnonymousAdmin() {
+		return true
+	}
+
+	member, err := bot.GetChatMember(ctx.EffectiveChat.Id, ctx.EffectiveSender.Id(), nil)
+	if err != nil {
+		slog.Error("Failed to get chat member!", slog.String("err", err.Error()))
+		return false
+	}
+
+	switch member.GetStatus() {
+	case "creator", "administrator":
+		return true
+	default:
+		return false
+	}
+}
+
+// deleteBridged deletes the bridged message along with the command.
+func (t *Telegram) deleteBridged(bot *gotgbot.Bot, ctx *ext.Context, key messageKey) error {
+	if _, err := bot.DeleteMessage(key.chatId, key.messageId, nil); err != nil {
+		_, err := ctx.EffectiveMessage.Reply(bot, "Failed to delete message: "+err.Error(), nil)
+		return err
+	}
+
+	t.history.remove(key)
+
+	if _, err := ctx.EffectiveMessage.Delete(bot, nil); err != nil {
+		slog.Warn("Failed to delete command", slog.String("err", err.Error()))
+	}
+
+	return nil
+}
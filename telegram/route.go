@@ -0,0 +1,126 @@
+package telegram
+
+import (
+	"fmt"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"slices"
+)
+
+// RouteOpts connects one game server with one Telegram chat thread.
+type RouteOpts struct {
+	ServerName  string
+	ChatId      int64
+	ThreadId    int64
+	Server      Server
+	ReceiveChan chan econ.Event
+	SendChan    chan string
+	Emoji       *EmojiConverter
+	// Events are the kinds of game events posted to the thread, nil
+	// means DefaultEvents
+	Events []econ.EventKind
+	// Channels are the chat channels posted to the thread, nil means
+	// DefaultChannels
+	Channels []econ.Channel
+}
+
+// DefaultEvents leaves out kills and /rank replies, which are noisy on
+// busy servers.
+var DefaultEvents = []econ.EventKind{
+	econ.EventChat,
+	econ.EventJoin,
+	econ.EventLeave,
+	econ.EventInfo,
+	econ.EventFinish,
+	econ.EventTeamFinish,
+	econ.EventRecord,
+	econ.EventPersonalRecord,
+	econ.EventMap,
+	econ.EventVote,
+	econ.EventVoteResult,
+	econ.EventOnline,
+	econ.EventOffline,
+}
+
+type routeKey struct {
+	chatId   int64
+	threadId int64
+}
+
+type route struct {
+	serverName    string
+	chatId        int64
+	threadId      int64
+	server        Server
+	receiveChan   chan econ.Event
+	sendChan      chan string
+	statusTrigger chan struct{}
+	outbox        *outbox
+	emoji         *EmojiConverter
+	senders       *senders
+	events        map[econ.EventKind]bool
+	channels      map[econ.Channel]bool
+	ballot        ballot
+}
+
+func newRoute(opts RouteOpts) (*route, error) {
+	kinds := opts.Events
+	if kinds == nil {
+		kinds = DefaultEvents
+	}
+
+	events := make(map[econ.EventKind]bool, len(kinds))
+	for _, kind := range kinds {
+		if !slices.Contains(econ.EventKinds, kind) {
+			return nil, fmt.Errorf("telegram: %v: unknown event %q", opts.ServerName, kind)
+		}
+		events[kind] = true
+	}
+
+	names := opts.Channels
+	if names == nil {
+		names = DefaultChannels
+	}
+
+	channels := make(map[econ.Channel]bool, len(names))
+	for _, channel := range names {
+		if !slices.Contains(econ.Channels, channel) {
+			return nil, fmt.Errorf("telegram: %v: unknown channel %q", opts.ServerName, channel)
+		}
+		channels[channel] = true
+	}
+
+	return &route{
+		serverName:    opts.ServerName,
+		chatId:        opts.ChatId,
+		threadId:      opts.ThreadId,
+		server:        opts.Server,
+		receiveChan:   opts.ReceiveChan,
+		sendChan:      opts.SendChan,
+		statusTrigger: make(chan struct{}, 1),
+		outbox:        newOutbox(),
+		emoji:         opts.Emoji,
+		senders:       newSenders(),
+		events:        events,
+		channels:      channels,
+	}, nil
+}
+
+// DefaultChannels keep team chat (spectators included) and whispers
+// private and skip server lines.
+var DefaultChannels = []econ.Channel{
+	econ.ChannelPublic,
+}
+
+// route finds the server bridged with the thread the update came from.
+func (t *Telegram) route(ctx *ext.Context) (*route, bool) {
+	if ctx.EffectiveMessage == nil || ctx.EffectiveChat == nil {
+		return nil, false
+	}
+
+	r, ok := t.routes[routeKey{
+		chatId:   ctx.EffectiveChat.Id,
+		threadId: ctx.EffectiveMessage.MessageThreadId,
+	}]
+	return r, ok
+}
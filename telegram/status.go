@@ -0,0 +1,186 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStatusInterval    = time.Minute
+	defaultStatusMinInterval = time.Second * 10
+)
+
+// StatusOpts configures the pinned status message. Updates are triggered
+// every Interval and by joins, leaves and reconnects, but the message is
+// never edited more often than once per MinInterval.
+type StatusOpts struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Interval    time.Duration `mapstructure:"interval"`
+	MinInterval time.Duration `mapstructure:"min_interval"`
+}
+
+func (r *route) triggerStatus() {
+	select {
+	case r.statusTrigger <- struct{}{}:
+	default:
+	}
+}
+
+func (t *Telegram) statusLoop(ctx context.Context, r *route) {
+	interval, minInterval := t.status.Interval, t.status.MinInterval
+	if interval <= 0 {
+		interval = defaultStatusInterval
+	}
+	if minInterval <= 0 {
+		minInterval = defaultStatusMinInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		last     string
+		lastEdit time.Time
+		pending  <-chan time.Time
+		known    knownStatus
+	)
+
+	for {
+		if wait := minInterval - time.Since(lastEdit); wait > 0 {
+			if pending == nil {
+				pending = time.After(wait)
+			}
+		} else {
+			text := statusText(ctx, r, &known)
+			if text != last {
+				err := t.updateStatus(r, text)
+				if err != nil {
+					slog.Error(
+						"Failed to update status message!",
+						slog.String("server", r.serverName),
+						slog.String(
+							"err",
+							err.Error(),
+						),
+					)
+				} else {
+					last = text
+					lastEdit = time.Now()
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.statusTrigger:
+		case <-pending:
+			pending = nil
+		}
+	}
+}
+
+// knownStatus is what the server last told about itself.
+type knownStatus struct {
+	mapName string
+	players []econ.Player
+}
+
+// statusText reports the server offline only if the connection is down.
+// A lookup that fails while it's up (e.g. the reply came after the exec
+// window) keeps what was known before.
+func statusText(ctx context.Context, r *route, known *knownStatus) string {
+	if !r.server.Connected() {
+		return FormatStatus(r.serverName, "", nil, false)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	if mapName, err := r.server.Map(queryCtx); err == nil {
+		known.mapName = mapName
+	} else {
+		slog.Debug(
+			"Failed to get map, keeping the last known",
+			slog.String("server", r.serverName),
+			slog.String("err", err.Error()),
+		)
+	}
+
+	if players, err := r.server.Players(queryCtx); err == nil {
+		known.players = players
+	} else {
+		slog.Debug(
+			"Failed to get players, keeping the last known",
+			slog.String("server", r.serverName),
+			slog.String("err", err.Error()),
+		)
+	}
+
+	return FormatStatus(r.serverName, known.mapName, known.players, true)
+}
+
+func (r *route) statusKey() string {
+	return fmt.Sprintf("status_message:%v:%v", r.chatId, r.threadId)
+}
+
+// updateStatus edits the pinned status message, creating and pinning a
+// new one if there is none yet or it was deleted.
+func (t *Telegram) updateStatus(r *route, text string) error {
+	var messageId int64
+	if _, err := t.store.Get(r.statusKey(), &messageId); err != nil {
+		return err
+	}
+
+	if messageId != 0 {
+		_, _, err := t.bot.EditMessageText(text, &gotgbot.EditMessageTextOpts{
+			ChatId:    r.chatId,
+			MessageId: messageId,
+		})
+		if err == nil || isTelegramError(err, "message is not modified") {
+			return nil
+		}
+		if !isTelegramError(err, "message to edit not found") {
+			return err
+		}
+	}
+
+	msg, err := t.bot.SendMessage(r.chatId, text, &gotgbot.SendMessageOpts{
+		MessageThreadId:     r.threadId,
+		DisableNotification: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = t.bot.PinChatMessage(r.chatId, msg.MessageId, &gotgbot.PinChatMessageOpts{
+		DisableNotification: true,
+	})
+	if err != nil {
+		slog.Warn(
+			"Failed to pin status message",
+			slog.String(
+				"err",
+				err.Error(),
+			),
+		)
+	}
+
+	return t.store.Set(r.statusKey(), msg.MessageId)
+}
+
+func isTelegramError(err error, description string) bool {
+	var tgErr *gotgbot.TelegramError
+	if !errors.As(err, &tgErr) {
+		return false
+	}
+
+	return strings.Contains(tgErr.Description, description)
+}
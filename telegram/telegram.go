@@ -2,38 +2,81 @@ package telegram
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"github.com/xbt573/tw-econ-telegram-bridge/store"
 	"log/slog"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const messageLimit = 4096
+
+// Server is the game server side of the bridge.
+type Server interface {
+	Exec(ctx context.Context, command string) ([]string, error)
+	Players(ctx context.Context) ([]econ.Player, error)
+	Map(ctx context.Context) (string, error)
+	Connected() bool
+}
+
+var ErrDuplicateRoute = errors.New("telegram: several servers are bridged with the same thread")
+
 type Telegram struct {
-	serverName  string
-	chatId      int64
-	threadId    int64
 	bot         *gotgbot.Bot
 	updater     *ext.Updater
-	receiveChan chan string
-	sendChan    chan string
+	routes      map[routeKey]*route
+	rconPolicy  RconPolicy
+	showAddress bool
+	store       *store.Store
+	status      StatusOpts
+	webhook     WebhookOpts
+	outbox      OutboxOpts
+	limiter     *limiter
+	templates   *Templates
+	history     *history
+	quoteLength int
+	mentions    MentionOpts
+	media       MediaOpts
+	edit        EditOpts
+	edits       *edits
+	vote        VoteOpts
 }
 
 type TelegramOpts struct {
 	Token       string
-	ServerName  string
-	ThreadId    int64
-	ChatId      int64
-	ReceiveChan chan string
-	SendChan    chan string
 	BotOpts     *gotgbot.BotOpts
+	Routes      []RouteOpts
+	RconUsers   []RconUser
+	ShowAddress bool
+	Store       *store.Store
+	Status      StatusOpts
+	Webhook     WebhookOpts
+	Outbox      OutboxOpts
+	Templates   *Templates
+	// QuoteLength is how much of a replied to message is quoted in game,
+	// zero disables quoting.
+	QuoteLength int
+	Mentions    MentionOpts
+	Media       MediaOpts
+	Edit        EditOpts
+	Vote        VoteOpts
 }
 
 func NewTelegram(opts TelegramOpts) (*Telegram, error) {
+	if err := opts.Webhook.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := opts.Media.validate(); err != nil {
+		return nil, err
+	}
+
 	bot, err := gotgbot.NewBot(opts.Token, opts.BotOpts)
 	if err != nil {
 		return nil, err
@@ -41,11 +84,41 @@ func NewTelegram(opts TelegramOpts) (*Telegram, error) {
 
 	telegram := &Telegram{
 		bot:         bot,
-		chatId:      opts.ChatId,
-		serverName:  opts.ServerName,
-		threadId:    opts.ThreadId,
-		sendChan:    opts.SendChan,
-		receiveChan: opts.ReceiveChan,
+		routes:      make(map[routeKey]*route, len(opts.Routes)),
+		rconPolicy:  NewRconPolicy(opts.RconUsers),
+		showAddress: opts.ShowAddress,
+		store:       opts.Store,
+		status:      opts.Status,
+		webhook:     opts.Webhook,
+		outbox:      opts.Outbox,
+		templates:   opts.Templates,
+		history:     newHistory(historySize),
+		quoteLength: opts.QuoteLength,
+		mentions:    opts.Mentions,
+		media:       opts.Media,
+		edit:        opts.Edit,
+		edits:       newEdits(),
+		vote:        opts.Vote,
+	}
+
+	minInterval := opts.Outbox.MinInterval
+	if minInterval <= 0 {
+		minInterval = defaultOutboxMinInterval
+	}
+	telegram.limiter = newLimiter(minInterval)
+
+	for _, x := range opts.Routes {
+		key := routeKey{chatId: x.ChatId, threadId: x.ThreadId}
+		if _, ok := telegram.routes[key]; ok {
+			return nil, ErrDuplicateRoute
+		}
+
+		r, err := newRoute(x)
+		if err != nil {
+			return nil, err
+		}
+
+		telegram.routes[key] = r
 	}
 
 	telegram.updater = ext.NewUpdater(&ext.UpdaterOpts{
@@ -64,37 +137,134 @@ func NewTelegram(opts TelegramOpts) (*Telegram, error) {
 	})
 
 	telegram.updater.Dispatcher.AddHandler(handlers.NewCommand("currentthreadid", telegram.GetThreadId))
+	telegram.updater.Dispatcher.AddHandler(handlers.NewCommand("rcon", telegram.Rcon))
+	telegram.updater.Dispatcher.AddHandler(handlers.NewCommand("players", telegram.Players))
+	telegram.updater.Dispatcher.AddHandler(handlers.NewCommand("retract", telegram.Retract))
+	telegram.updater.Dispatcher.AddHandler(handlers.NewCommand("del", telegram.Del))
+	telegram.updater.Dispatcher.AddHandler(handlers.NewCallback(isVoteCallback, telegram.OnVote))
 	telegram.updater.Dispatcher.AddHandler(handlers.NewMessage(message.Text, telegram.OnText))
 	telegram.updater.Dispatcher.AddHandler(handlers.NewMessage(message.All, telegram.OnMedia))
+	telegram.updater.Dispatcher.AddHandler(handlers.Message{
+		AllowEdited: true,
+		Filter:      isEditable,
+		Response:    telegram.OnEdit,
+	})
 
 	return telegram, nil
 }
 
 func (t *Telegram) OnText(bot *gotgbot.Bot, ctx *ext.Context) error {
-	if ctx.EffectiveMessage.MessageThreadId != t.threadId {
+	r, ok := t.route(ctx)
+	if !ok {
 		return nil
 	}
 
-	username := ctx.EffectiveSender.Name()
-	text := strings.ReplaceAll(ctx.EffectiveMessage.Text, "\"", "\\\"")
-	text = ReplaceFromEmoji(text)
+	data := TemplateData{
+		Server:  r.serverName,
+		Name:    ctx.EffectiveSender.Name(),
+		Text:    r.emoji.ToGame(ctx.EffectiveMessage.Text),
+		Time:    time.Unix(ctx.EffectiveMessage.Date, 0),
+		Forward: r.emoji.ToGame(forwardOrigin(ctx.EffectiveMessage, t.media)),
+	}
+
+	kind := MessageText
+	if t.replyContext(r, ctx.EffectiveMessage, &data) {
+		kind = MessageReply
+	}
+
+	text, ok := t.templates.Game(kind, data)
+	if ok {
+		t.relay(r, ctx, text)
+	}
 
-	t.sendChan <- fmt.Sprintf("%v: %v", username, text)
 	return nil
 }
+
 func (t *Telegram) OnMedia(bot *gotgbot.Bot, ctx *ext.Context) error {
-	if ctx.EffectiveMessage.MessageThreadId != t.threadId {
+	r, ok := t.route(ctx)
+	if !ok {
+		return nil
+	}
+
+	media, ok := describeMedia(r, ctx.EffectiveMessage, t.media)
+	if !ok {
 		return nil
 	}
 
-	username := ctx.EffectiveSender.Name()
-	text := strings.ReplaceAll(ctx.EffectiveMessage.Caption, "\"", "\\\"")
-	text = ReplaceFromEmoji(text)
+	data := TemplateData{
+		Server:  r.serverName,
+		Name:    ctx.EffectiveSender.Name(),
+		Text:    r.emoji.ToGame(ctx.EffectiveMessage.Caption),
+		Time:    time.Unix(ctx.EffectiveMessage.Date, 0),
+		Media:   media,
+		Forward: r.emoji.ToGame(forwardOrigin(ctx.EffectiveMessage, t.media)),
+	}
+	t.replyContext(r, ctx.EffectiveMessage, &data)
+
+	text, ok := t.templates.Game(MessageMedia, data)
+	if ok {
+		t.relay(r, ctx, text)
+	}
 
-	t.sendChan <- fmt.Sprintf("%v: [MEDIA] %v", username, text)
 	return nil
 }
 
+// relay sends a Telegram message to the game and remembers it, so game
+// players can answer it and moderators can retract it.
+func (t *Telegram) relay(r *route, ctx *ext.Context, text string) {
+	var (
+		msg    = ctx.EffectiveMessage
+		sender = ctx.EffectiveSender
+	)
+
+	t.history.add(messageKey{chatId: msg.Chat.Id, messageId: msg.MessageId}, bridgedMessage{
+		Sender: sender.Name(),
+		Text:   text,
+		Time:   time.Now(),
+	})
+
+	if t.mentions.Enabled {
+		r.senders.add([]string{sender.Name(), sender.Username(), sender.FirstName()}, msg.MessageId)
+	}
+
+	r.sendChan <- text
+}
+
+// replyContext fills in who the message replies to and a quote of it,
+// reporting false if it isn't a reply. Replies to bridged game lines are
+// attributed to the player who wrote them.
+func (t *Telegram) replyContext(r *route, msg *gotgbot.Message, data *TemplateData) bool {
+	reply := msg.ReplyToMessage
+
+	// in forum topics every message replies to the topic itself
+	if reply == nil || reply.MessageId == msg.MessageThreadId || reply.ForumTopicCreated != nil {
+		return false
+	}
+
+	data.ReplyTo = reply.GetSender().Name()
+	quote := reply.Text
+	if quote == "" {
+		quote = reply.Caption
+	}
+
+	if reply.From != nil && reply.From.Id == t.bot.Id {
+		bridged, ok := t.history.get(messageKey{chatId: reply.Chat.Id, messageId: reply.MessageId})
+		if ok {
+			quote = bridged.Text
+			if bridged.Player != "" {
+				data.ReplyTo = bridged.Player
+			}
+		}
+	}
+
+	if t.quoteLength > 0 && quote != "" {
+		quote = strings.Join(strings.Fields(quote), " ")
+		data.Quote = r.emoji.ToGame(truncate(quote, t.quoteLength))
+	}
+
+	return true
+}
+
 func (t *Telegram) GetThreadId(bot *gotgbot.Bot, ctx *ext.Context) error {
 	_, err := ctx.EffectiveMessage.Reply(bot, strconv.FormatInt(ctx.EffectiveMessage.MessageThreadId, 10), nil)
 	if err != nil {
@@ -104,36 +274,132 @@ func (t *Telegram) GetThreadId(bot *gotgbot.Bot, ctx *ext.Context) error {
 	return nil
 }
 
-func (t *Telegram) Start(ctx context.Context) error {
-	err := t.updater.StartPolling(t.bot, &ext.PollingOpts{
-		DropPendingUpdates: true,
-		GetUpdatesOpts: gotgbot.GetUpdatesOpts{
-			Timeout: 9,
-			RequestOpts: &gotgbot.RequestOpts{
-				Timeout: time.Second * 10,
-			},
-		},
-	})
+func (t *Telegram) Rcon(bot *gotgbot.Bot, ctx *ext.Context) error {
+	r, ok := t.route(ctx)
+	if !ok || ctx.EffectiveUser == nil {
+		return nil
+	}
+
+	_, command, _ := strings.Cut(ctx.EffectiveMessage.Text, " ")
+	command = strings.TrimSpace(command)
+
+	if err := t.rconPolicy.Check(ctx.EffectiveUser.Id, command); err != nil {
+		slog.Warn(
+			"Rejected rcon command",
+			slog.String("server", r.serverName),
+			slog.Int64("user", ctx.EffectiveUser.Id),
+			slog.String("command", command),
+			slog.String("err", err.Error()),
+		)
+		_, err := ctx.EffectiveMessage.Reply(bot, err.Error(), nil)
+		return err
+	}
+
+	slog.Info(
+		"Running rcon command",
+		slog.String("server", r.serverName),
+		slog.Int64("user", ctx.EffectiveUser.Id),
+		slog.String("command", command),
+	)
+
+	execCtx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	output, err := r.server.Exec(execCtx, command)
+	if err != nil {
+		_, err := ctx.EffectiveMessage.Reply(bot, "Failed to run command: "+err.Error(), nil)
+		return err
+	}
+
+	text := strings.Join(output, "\n")
+	if text == "" {
+		text = "(no output)"
+	}
+
+	_, err = ctx.EffectiveMessage.Reply(bot, truncateMessage(text), nil)
+	return err
+}
+
+func (t *Telegram) Players(bot *gotgbot.Bot, ctx *ext.Context) error {
+	r, ok := t.route(ctx)
+	if !ok {
+		return nil
+	}
+
+	queryCtx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
 
+	players, err := r.server.Players(queryCtx)
 	if err != nil {
+		_, err := ctx.EffectiveMessage.Reply(bot, "Failed to get players: "+err.Error(), nil)
+		return err
+	}
+
+	_, err = ctx.EffectiveMessage.Reply(bot, truncateMessage(FormatPlayers(players, t.showAddress)), nil)
+	return err
+}
+
+func (t *Telegram) Start(ctx context.Context) error {
+	if err := t.startUpdates(); err != nil {
 		return err
 	}
 
 	go t.updater.Idle()
-	defer t.updater.Stop()
+	defer t.stopUpdates()
+
+	for _, r := range t.routes {
+		if t.status.Enabled {
+			go t.statusLoop(ctx, r)
+		}
+
+		go t.deliver(ctx, r)
+		go t.forward(ctx, r)
+	}
 
+	<-ctx.Done()
+	return nil
+}
+
+// forward queues events of one server for its thread.
+func (t *Telegram) forward(ctx context.Context, r *route) {
 	for {
 		select {
 		case <-ctx.Done():
-			return nil
-		case x := <-t.receiveChan:
-			msg := ReplaceToEmoji(x)
-			_, err := t.bot.SendMessage(t.chatId, msg, &gotgbot.SendMessageOpts{
-				MessageThreadId: t.threadId,
-			})
-			if err != nil {
-				return err
+			return
+		case x := <-r.receiveChan:
+			switch x.Kind {
+			case econ.EventJoin, econ.EventLeave, econ.EventMap, econ.EventOnline, econ.EventOffline:
+				r.triggerStatus()
+			}
+
+			if !r.events[x.Kind] || (x.Channel != "" && !r.channels[x.Channel]) {
+				continue
+			}
+
+			// before rendering, escaping would break shortcodes
+			x.Player = r.emoji.ToTelegram(x.Player)
+			x.Text = r.emoji.ToTelegram(x.Text)
+			x.Victim = r.emoji.ToTelegram(x.Victim)
+
+			text, ok := t.templates.Telegram(r.serverName, x)
+			if !ok {
+				continue
+			}
+
+			out := outgoing{
+				text:        text,
+				raw:         x.Text,
+				ballot:      x.Kind == econ.EventVote && t.vote.Buttons,
+				closeBallot: x.Kind == econ.EventVoteResult,
+			}
+			if x.Kind == econ.EventChat {
+				out.player = x.Player
+				if t.mentions.Enabled {
+					out.replyTo, _ = r.senders.addressee(x.Text, t.mentions.MaxAge)
+				}
 			}
+
+			r.outbox.push(out)
 		}
 	}
 }
@@ -0,0 +1,314 @@
+package telegram
+
+import (
+	"fmt"
+	"github.com/xbt573/tw-econ-telegram-bridge/econ"
+	"log/slog"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kinds of Telegram messages sent to the game.
+const (
+	MessageText    = "text"
+	MessageMedia   = "media"
+	MessageReply   = "reply"
+	MessageEdit    = "edit"
+	MessageRetract = "retract"
+)
+
+var defaultTelegramTemplates = map[econ.EventKind]string{
+	econ.EventChat:    "{{if ne .Channel \"public\"}}[{{.Channel}}] {{end}}{{.Name}}: {{.Text}}",
+	econ.EventJoin:    "{{.Name}} joined the game",
+	econ.EventLeave:   "{{.Name}} left the game",
+	econ.EventInfo:    "{{with .Name}}{{.}} {{end}}{{.Text}}",
+	econ.EventOnline:  "Server is back online",
+	econ.EventOffline: "Server is offline, reconnecting...",
+
+	econ.EventKill:           "{{if eq .Name .Victim}}{{.Name}} died{{else}}{{.Name}} killed {{.Victim}}{{end}} ({{.Weapon}})",
+	econ.EventFinish:         "{{.Name}} finished in {{.Duration}}",
+	econ.EventTeamFinish:     "Team {{.Name}} finished in {{.Duration}}",
+	econ.EventRecord:         "{{.Name}} set a new server record: {{.Duration}}",
+	econ.EventPersonalRecord: "New personal record, {{.Duration}} better",
+	econ.EventRank:           "{{.Name}} is ranked #{{.Rank}} with {{.Duration}}",
+
+	econ.EventMap:        "Map changed to {{.Map}}",
+	econ.EventVote:       "{{.Name}} called a vote: {{.Text}}{{if .Reason}} ({{.Reason}}){{end}}",
+	econ.EventVoteResult: "Vote {{.Result}}{{if or .Yes .No}} ({{.Yes}} yes, {{.No}} no){{end}}",
+}
+
+var htmlTelegramTemplates = map[econ.EventKind]string{
+	econ.EventChat:    "{{if ne .Channel \"public\"}}[{{.Channel}}] {{end}}<b>{{.Name}}</b>: {{.Text}}",
+	econ.EventJoin:    "<i>{{.Name}} joined the game</i>",
+	econ.EventLeave:   "<i>{{.Name}} left the game</i>",
+	econ.EventInfo:    "<i>{{with .Name}}{{.}} {{end}}{{.Text}}</i>",
+	econ.EventOnline:  "<i>Server is back online</i>",
+	econ.EventOffline: "<i>Server is offline, reconnecting...</i>",
+
+	econ.EventKill:           "<i>{{if eq .Name .Victim}}{{.Name}} died{{else}}{{.Name}} killed {{.Victim}}{{end}} ({{.Weapon}})</i>",
+	econ.EventFinish:         "🏁 <b>{{.Name}}</b> finished in <code>{{.Duration}}</code>",
+	econ.EventTeamFinish:     "🏁 Team <b>{{.Name}}</b> finished in <code>{{.Duration}}</code>",
+	econ.EventRecord:         "🏆 <b>{{.Name}}</b> set a new server record: <code>{{.Duration}}</code>",
+	econ.EventPersonalRecord: "<i>New personal record, <code>{{.Duration}}</code> better</i>",
+	econ.EventRank:           "<i>{{.Name}} is ranked #{{.Rank}} with <code>{{.Duration}}</code></i>",
+
+	econ.EventMap:        "🗺 Map changed to <b>{{.Map}}</b>",
+	econ.EventVote:       "🗳 <b>{{.Name}}</b> called a vote: <b>{{.Text}}</b>{{if .Reason}} <i>({{.Reason}})</i>{{end}}",
+	econ.EventVoteResult: "<i>Vote {{.Result}}{{if or .Yes .No}} ({{.Yes}} yes, {{.No}} no){{end}}</i>",
+}
+
+var markdownTelegramTemplates = map[econ.EventKind]string{
+	econ.EventChat:    "{{if ne .Channel \"public\"}}\\[{{.Channel}}\\] {{end}}*{{.Name}}*: {{.Text}}",
+	econ.EventJoin:    "_{{.Name}} joined the game_",
+	econ.EventLeave:   "_{{.Name}} left the game_",
+	econ.EventInfo:    "_{{with .Name}}{{.}} {{end}}{{.Text}}_",
+	econ.EventOnline:  "_Server is back online_",
+	econ.EventOffline: "_Server is offline, reconnecting\\.\\.\\._",
+
+	econ.EventKill:           "_{{if eq .Name .Victim}}{{.Name}} died{{else}}{{.Name}} killed {{.Victim}}{{end}} \\({{.Weapon}}\\)_",
+	econ.EventFinish:         "🏁 *{{.Name}}* finished in `{{.Duration}}`",
+	econ.EventTeamFinish:     "🏁 Team *{{.Name}}* finished in `{{.Duration}}`",
+	econ.EventRecord:         "🏆 *{{.Name}}* set a new server record: `{{.Duration}}`",
+	econ.EventPersonalRecord: "_New personal record, `{{.Duration}}` better_",
+	econ.EventRank:           "_{{.Name}} is ranked \\#{{.Rank}} with `{{.Duration}}`_",
+
+	econ.EventMap:        "🗺 Map changed to *{{.Map}}*",
+	econ.EventVote:       "🗳 *{{.Name}}* called a vote: *{{.Text}}*{{if .Reason}} _\\({{.Reason}}\\)_{{end}}",
+	econ.EventVoteResult: "_Vote {{.Result}}{{if or .Yes .No}} \\({{.Yes}} yes, {{.No}} no\\){{end}}_",
+}
+
+var defaultGameTemplates = map[string]string{
+	MessageText:    "{{.Name}}: {{if .Forward}}[FWD {{.Forward}}] {{end}}{{.Text}}",
+	MessageMedia:   "{{.Name}}: {{if .Forward}}[FWD {{.Forward}}] {{end}}{{.Media}}{{if .Text}} {{.Text}}{{end}}",
+	MessageReply:   "{{.Name}} (↪ {{.ReplyTo}}{{if .Quote}}: {{.Quote}}{{end}}): {{.Text}}",
+	MessageEdit:    "{{.Name}} (edited): {{.Text}}",
+	MessageRetract: "A message of {{.Name}} was removed by a moderator",
+}
+
+// TemplateData is what message templates are executed with. Fields that
+// don't apply to the message are left zero.
+type TemplateData struct {
+	Server   string
+	Kind     string
+	Name     string
+	Text     string
+	Team     int
+	ClientId int
+	Time     time.Time
+	// Channel of chat and server lines, see econ.Channel
+	Channel string
+
+	// who a Telegram message replies to and the start of that message
+	ReplyTo string
+	Quote   string
+
+	// kills and DDRace times formatted like "01:23.450"
+	Victim   string
+	Weapon   string
+	Duration string
+	Rank     int
+
+	// map changes and votes, see econ.Event
+	Map    string
+	Reason string
+	Result string
+	Yes    int
+	No     int
+
+	// label of Telegram media like "[VOICE 0:05]" and where a forwarded
+	// message comes from
+	Media   string
+	Forward string
+}
+
+// TemplateOpts holds user templates by kind, overriding the defaults.
+// Telegram templates render game events, game templates render Telegram
+// messages. A template rendering to an empty string drops the message.
+//
+// ParseMode ("HTML" or "MarkdownV2") enables formatting in Telegram
+// templates. Fields are escaped before they are inserted, so only the
+// template itself can add markup.
+type TemplateOpts struct {
+	ParseMode string            `mapstructure:"parse_mode"`
+	Telegram  map[string]string `mapstructure:"telegram"`
+	Game      map[string]string `mapstructure:"game"`
+}
+
+type Templates struct {
+	parseMode string
+	telegram  map[string]*template.Template
+	game      map[string]*template.Template
+}
+
+func NewTemplates(opts TemplateOpts) (*Templates, error) {
+	parseMode, err := parseParseMode(opts.ParseMode)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := defaultTelegramTemplates
+	switch parseMode {
+	case ParseModeHTML:
+		defaults = htmlTelegramTemplates
+	case ParseModeMarkdownV2:
+		defaults = markdownTelegramTemplates
+	}
+
+	telegramDefaults := make(map[string]string, len(defaults))
+	for kind, text := range defaults {
+		telegramDefaults[string(kind)] = text
+	}
+
+	telegram, err := parseTemplates("telegram", telegramDefaults, opts.Telegram)
+	if err != nil {
+		return nil, err
+	}
+
+	game, err := parseTemplates("game", defaultGameTemplates, opts.Game)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Templates{
+		parseMode: parseMode,
+		telegram:  telegram,
+		game:      game,
+	}, nil
+}
+
+// sampleData fills every field, so checking a template with it and with
+// zero data takes both sides of "if" actions.
+var sampleData = TemplateData{
+	Server:   "server",
+	Name:     "name",
+	Text:     "text",
+	Team:     1,
+	ClientId: 1,
+	Time:     time.Unix(0, 0),
+	Channel:  string(econ.ChannelPublic),
+	ReplyTo:  "reply_to",
+	Quote:    "quote",
+	Victim:   "victim",
+	Weapon:   "weapon",
+	Duration: "01:23.450",
+	Rank:     1,
+	Map:      "map",
+	Reason:   "reason",
+	Result:   "passed",
+	Yes:      1,
+	No:       1,
+	Media:    "[PHOTO]",
+	Forward:  "forward",
+}
+
+// parseTemplates parses defaults overridden by user templates, and checks
+// them against empty and sample data so mistakes like unknown fields are
+// reported at startup instead of on the first message.
+func parseTemplates(direction string, defaults, user map[string]string) (map[string]*template.Template, error) {
+	sources := make(map[string]string, len(defaults))
+	for kind, text := range defaults {
+		sources[kind] = text
+	}
+
+	for kind, text := range user {
+		if _, ok := defaults[kind]; !ok {
+			return nil, fmt.Errorf(
+				"templates: unknown %v template %q, expected one of %v",
+				direction,
+				kind,
+				strings.Join(sortedKeys(defaults), ", "),
+			)
+		}
+
+		sources[kind] = text
+	}
+
+	templates := make(map[string]*template.Template, len(sources))
+	for kind, text := range sources {
+		tmpl, err := template.New(direction + "." + kind).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("templates: %w", err)
+		}
+
+		sample := sampleData
+		sample.Kind = kind
+
+		for _, data := range []TemplateData{{Kind: kind}, sample} {
+			if err := tmpl.Execute(&strings.Builder{}, data); err != nil {
+				return nil, fmt.Errorf("templates: %w", err)
+			}
+		}
+
+		templates[kind] = tmpl
+	}
+
+	return templates, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (t *Templates) ParseMode() string {
+	return t.parseMode
+}
+
+// Telegram renders a game event, reporting false if it shouldn't be
+// posted.
+func (t *Templates) Telegram(server string, event econ.Event) (string, bool) {
+	return execute(t.telegram, TemplateData{
+		Server:   Escape(t.parseMode, server),
+		Kind:     string(event.Kind),
+		Name:     Escape(t.parseMode, event.Player),
+		Text:     Escape(t.parseMode, event.Text),
+		Team:     event.Team,
+		ClientId: event.ClientId,
+		Time:     event.Time,
+		Channel:  string(event.Channel),
+		Victim:   Escape(t.parseMode, event.Victim),
+		Weapon:   Escape(t.parseMode, event.Weapon),
+		Duration: Escape(t.parseMode, FormatRaceTime(event.Duration)),
+		Rank:     event.Rank,
+		Map:      Escape(t.parseMode, event.Map),
+		Reason:   Escape(t.parseMode, event.Reason),
+		Result:   Escape(t.parseMode, event.Result),
+		Yes:      event.Yes,
+		No:       event.No,
+	})
+}
+
+// Game renders a Telegram message of the given kind.
+func (t *Templates) Game(kind string, data TemplateData) (string, bool) {
+	data.Kind = kind
+	return execute(t.game, data)
+}
+
+func execute(templates map[string]*template.Template, data TemplateData) (string, bool) {
+	tmpl, ok := templates[data.Kind]
+	if !ok {
+		return "", false
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		// templates are checked on startup, but not every path through
+		// them is
+		slog.Error(
+			"Failed to render template!",
+			slog.String("template", tmpl.Name()),
+			slog.String("err", err.Error()),
+		)
+		return "", false
+	}
+
+	text := builder.String()
+	return text, text != ""
+}
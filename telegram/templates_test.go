@@ -0,0 +1,16 @@
+package telegram
+
+import "testing"
+
+func TestNewTemplatesChecksBranches(t *testing.T) {
+	_, err := NewTemplates(TemplateOpts{
+		Game: map[string]string{MessageText: "{{.Name}}: {{if .Forward}}{{.Forwarded}}{{end}}{{.Text}}"},
+	})
+	if err == nil {
+		t.Error("unknown field inside an if branch wasn't reported")
+	}
+
+	if _, err := NewTemplates(TemplateOpts{}); err != nil {
+		t.Errorf("default templates: %v", err)
+	}
+}
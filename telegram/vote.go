@@ -0,0 +1,109 @@
+package telegram
+
+import (
+	"context"
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const votePrefix = "vote:"
+
+// VoteOpts configures voting from Telegram. With Buttons, vote
+// announcements get yes and no buttons that run "vote yes" or "vote no"
+// for users whose rcon permissions allow the vote command.
+type VoteOpts struct {
+	Buttons bool `mapstructure:"buttons"`
+}
+
+var voteKeyboard = gotgbot.InlineKeyboardMarkup{
+	InlineKeyboard: [][]gotgbot.InlineKeyboardButton{{
+		{Text: "👍 Yes", CallbackData: votePrefix + "yes"},
+		{Text: "👎 No", CallbackData: votePrefix + "no"},
+	}},
+}
+
+// ballot is the message with buttons for the vote running on a server.
+type ballot struct {
+	mu        sync.Mutex
+	messageId int64
+}
+
+func (b *ballot) open(messageId int64) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous := b.messageId
+	b.messageId = messageId
+	return previous
+}
+
+func (b *ballot) close() int64 {
+	return b.open(0)
+}
+
+// closeBallot removes the buttons from a vote message once the vote is
+// over.
+func (t *Telegram) closeBallot(r *route, messageId int64) {
+	if messageId == 0 {
+		return
+	}
+
+	_, _, err := t.bot.EditMessageReplyMarkup(&gotgbot.EditMessageReplyMarkupOpts{
+		ChatId:    r.chatId,
+		MessageId: messageId,
+		// an empty keyboard, a missing one is rejected
+		ReplyMarkup: gotgbot.InlineKeyboardMarkup{InlineKeyboard: [][]gotgbot.InlineKeyboardButton{}},
+	})
+	if err != nil && !isTelegramError(err, "message is not modified") {
+		slog.Warn(
+			"Failed to remove vote buttons",
+			slog.String("server", r.serverName),
+			slog.String("err", err.Error()),
+		)
+	}
+}
+
+func isVoteCallback(query *gotgbot.CallbackQuery) bool {
+	return strings.HasPrefix(query.Data, votePrefix)
+}
+
+func (t *Telegram) OnVote(bot *gotgbot.Bot, ctx *ext.Context) error {
+	query := ctx.CallbackQuery
+
+	r, ok := t.route(ctx)
+	if !ok {
+		_, err := query.Answer(bot, nil)
+		return err
+	}
+
+	command := "vote " + strings.TrimPrefix(query.Data, votePrefix)
+	if err := t.rconPolicy.Check(query.From.Id, command); err != nil {
+		_, err := query.Answer(bot, &gotgbot.AnswerCallbackQueryOpts{
+			Text:      err.Error(),
+			ShowAlert: true,
+		})
+		return err
+	}
+
+	slog.Info(
+		"Voting from Telegram",
+		slog.String("server", r.serverName),
+		slog.Int64("user", query.From.Id),
+		slog.String("command", command),
+	)
+
+	execCtx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	text := "Done: " + command
+	if _, err := r.server.Exec(execCtx, command); err != nil {
+		text = "Failed to vote: " + err.Error()
+	}
+
+	_, err := query.Answer(bot, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+	return err
+}
@@ -0,0 +1,99 @@
+package telegram
+
+import (
+	"errors"
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrWebhookUrl = errors.New("telegram: webhook url must be an absolute https url")
+
+// WebhookOpts switches update delivery from long polling to a webhook.
+// Url is the public address Telegram posts to, its path is served on
+// Listen, so a reverse proxy has to keep the path intact. Cert and Key
+// enable TLS on the listener itself.
+type WebhookOpts struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Listen  string `mapstructure:"listen"`
+	Url     string `mapstructure:"url"`
+	Secret  string `mapstructure:"secret"`
+	Cert    string `mapstructure:"cert"`
+	Key     string `mapstructure:"key"`
+}
+
+func (w WebhookOpts) validate() error {
+	if !w.Enabled {
+		return nil
+	}
+
+	u, err := url.Parse(w.Url)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return ErrWebhookUrl
+	}
+
+	return nil
+}
+
+func (w WebhookOpts) path() string {
+	u, _ := url.Parse(w.Url)
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// startUpdates starts receiving updates either by polling or by webhook.
+func (t *Telegram) startUpdates() error {
+	if !t.webhook.Enabled {
+		return t.updater.StartPolling(t.bot, &ext.PollingOpts{
+			DropPendingUpdates: true,
+			GetUpdatesOpts: gotgbot.GetUpdatesOpts{
+				Timeout: 9,
+				RequestOpts: &gotgbot.RequestOpts{
+					Timeout: time.Second * 10,
+				},
+			},
+		})
+	}
+
+	err := t.updater.StartWebhook(t.bot, t.webhook.path(), ext.WebhookOpts{
+		ListenAddr:        t.webhook.Listen,
+		ReadHeaderTimeout: time.Second * 10,
+		CertFile:          t.webhook.Cert,
+		KeyFile:           t.webhook.Key,
+		SecretToken:       t.webhook.Secret,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = t.bot.SetWebhook(t.webhook.Url, &gotgbot.SetWebhookOpts{
+		DropPendingUpdates: true,
+		SecretToken:        t.webhook.Secret,
+	})
+	if err != nil {
+		t.updater.Stop()
+		return err
+	}
+
+	slog.Info("Listening for webhook updates", slog.String("listen", t.webhook.Listen))
+	return nil
+}
+
+func (t *Telegram) stopUpdates() {
+	if t.webhook.Enabled {
+		_, err := t.bot.DeleteWebhook(nil)
+		if err != nil {
+			slog.Error(
+				"Failed to delete webhook!",
+				slog.String(
+					"err",
+					err.Error(),
+				),
+			)
+		}
+	}
+
+	t.updater.Stop()
+}